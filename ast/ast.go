@@ -1,7 +1,13 @@
 package ast
 
-import "../token"
-import "bytes"
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"../token"
+)
 
 type Node interface {
 	TokenLiteral() string
@@ -20,6 +26,12 @@ type Expression interface {
 	expressionNode()
 }
 
+// TypeExpr is a type annotation of a declaration.
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
 // Program is a top node of an AST.
 // Contains a slice of statement nodes.
 type Program struct {
@@ -45,13 +57,31 @@ func (prg *Program) String() string {
 	return out.String()
 }
 
+// CommentGroup is a sequence of "///" doc comments without other tokens in between.
+type CommentGroup struct {
+	List []token.Token // The "DOC_COMMENT" tokens.
+}
+
+// Text returns the text of the doc comments without the "///" markers.
+func (cg *CommentGroup) Text() string {
+	lines := make([]string, len(cg.List))
+	for i, comment := range cg.List {
+		line := strings.TrimPrefix(comment.Literal, "///")
+		lines[i] = strings.TrimPrefix(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
 // DeclareStatement struct.
-// It consists of a "DECLARE" token, with an identifier and an expression.
-// as <identifier> = <expression>
+// It consists of a "DECLARE" token, with an identifier, an optional type and an expression.
+// The expression can be left out, if the type is given.
+// as <identifier>: <type> = <expression>
 type DeclareStatement struct {
 	Token token.Token // The "DECLARE" token.
 	Name  *Identifier
+	Type  TypeExpr // The type annotation, or nil.
 	Value Expression
+	Doc   *CommentGroup // The doc comments before the statement, or nil.
 }
 
 func (ds *DeclareStatement) statementNode()       {}
@@ -61,10 +91,16 @@ func (ds *DeclareStatement) String() string {
 
 	out.WriteString(ds.TokenLiteral() + " ")
 	out.WriteString(ds.Name.String())
-	out.WriteString(" = ")
+
+	if ds.Type != nil {
+		out.WriteString(": " + ds.Type.String())
+	}
 
 	if ds.Value != nil {
+		out.WriteString(" = ")
 		out.WriteString(ds.Value.String())
+	} else if ds.Type == nil {
+		out.WriteString(" = ")
 	}
 
 	out.WriteString(";")
@@ -98,10 +134,10 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(rs.TokenLiteral() + " ")
+	out.WriteString(rs.TokenLiteral())
 
 	if rs.Value != nil {
-		out.WriteString(rs.Value.String())
+		out.WriteString(" " + rs.Value.String())
 	}
 
 	out.WriteString(";")
@@ -134,6 +170,57 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral is an expression with a value of type float64.
+// <float>
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+// StringLiteral is an expression with a value of type string.
+// The value holds the string with its escape sequences already decoded.
+// "<string>"
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return quote(sl.Value) }
+
+// Quotes the string value and escapes it, so that it can be lexed back again.
+func quote(value string) string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, r := range value {
+		switch r {
+		case '\n':
+			out.WriteString("\\n")
+		case '\t':
+			out.WriteString("\\t")
+		case '"':
+			out.WriteString("\\\"")
+		case '\\':
+			out.WriteString("\\\\")
+		default:
+			if unicode.IsPrint(r) {
+				out.WriteRune(r)
+			} else {
+				fmt.Fprintf(&out, "\\u{%x}", r)
+			}
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
 type PrefixExpression struct {
 	Token    token.Token // The prefix token, "-5" or "!true"
 	Right    Expression
@@ -176,6 +263,30 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// LogicalExpression is an expression with a short-circuit operator, "&&" or "||".
+// Unlike in an InfixExpression, the right expression is evaluated only when the left one doesn't decide the result.
+// <expression> <operator> <expression>
+type LogicalExpression struct {
+	Token    token.Token // The logical operator token, "a && b" or "a || b".
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode()      {}
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // Boolean is a type that holds a value of 'true' or 'false'.
 // <boolean>
 type Boolean struct {
@@ -232,3 +343,501 @@ func (bs *BlockStatement) String() string {
 
 	return out.String()
 }
+
+// FunctionLiteral is an anonymous function with parameters and a body.
+// fn(<parameters>) { <body> }
+type FunctionLiteral struct {
+	Token      token.Token // The "fn" token.
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range fl.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString(fl.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(fl.Body.String())
+
+	return out.String()
+}
+
+// CallExpression is a call of a function with arguments.
+// <expression>(<arguments>)
+type CallExpression struct {
+	Token     token.Token // The "(" token.
+	Function  Expression  // An identifier or a function literal.
+	Arguments []Expression
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, arg := range ce.Arguments {
+		args = append(args, arg.String())
+	}
+
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// FunctionDeclaration is a named function with typed parameters and an optional return type.
+// fn <identifier>(<parameters>) of <type> { <body> }
+type FunctionDeclaration struct {
+	Token      token.Token // The "fn" token.
+	Name       *Identifier
+	Parameters []*Parameter
+	ReturnType TypeExpr // The type after "of", or nil.
+	Body       *BlockStatement
+	Doc        *CommentGroup // The doc comments before the declaration, or nil.
+}
+
+func (fd *FunctionDeclaration) statementNode()       {}
+func (fd *FunctionDeclaration) TokenLiteral() string { return fd.Token.Literal }
+func (fd *FunctionDeclaration) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range fd.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString(fd.TokenLiteral() + " ")
+	out.WriteString(fd.Name.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+
+	if fd.ReturnType != nil {
+		out.WriteString("of " + fd.ReturnType.String() + " ")
+	}
+
+	out.WriteString(fd.Body.String())
+
+	return out.String()
+}
+
+// Parameter is a function parameter with a type.
+// <identifier>: <type>
+type Parameter struct {
+	Name *Identifier
+	Type TypeExpr
+}
+
+func (p *Parameter) TokenLiteral() string { return p.Name.TokenLiteral() }
+func (p *Parameter) String() string       { return p.Name.String() + ": " + p.Type.String() }
+
+// SimpleType is one of the built-in types.
+// int, float, string or bool
+type SimpleType struct {
+	Token token.Token // The "IDENT" token.
+	Name  string
+}
+
+func (st *SimpleType) typeNode()            {}
+func (st *SimpleType) TokenLiteral() string { return st.Token.Literal }
+func (st *SimpleType) String() string       { return st.Name }
+
+// NamedType is a type declared by the user, such as a struct.
+// <identifier>
+type NamedType struct {
+	Token token.Token // The "IDENT" token.
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// FunctionType is a type of functions with the parameter types and an optional return type.
+// (<types>) <type>
+type FunctionType struct {
+	Token      token.Token // The "(" token.
+	Parameters []TypeExpr
+	Return     TypeExpr // The return type, or nil.
+}
+
+func (ft *FunctionType) typeNode()            {}
+func (ft *FunctionType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FunctionType) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range ft.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+
+	if ft.Return != nil {
+		out.WriteString(" " + ft.Return.String())
+	}
+
+	return out.String()
+}
+
+// ArrayType is a type of arrays with elements of the same type.
+// []<type>
+type ArrayType struct {
+	Token   token.Token // The "[" token.
+	Element TypeExpr
+}
+
+func (at *ArrayType) typeNode()            {}
+func (at *ArrayType) TokenLiteral() string { return at.Token.Literal }
+func (at *ArrayType) String() string       { return "[]" + at.Element.String() }
+
+// StructDeclaration is a declaration of a record type with typed fields.
+// struct <identifier> { <field>: <type>, ... }
+type StructDeclaration struct {
+	Token  token.Token // The "struct" token.
+	Name   *Identifier
+	Fields []*StructField
+	Doc    *CommentGroup // The doc comments before the declaration, or nil.
+}
+
+func (sd *StructDeclaration) statementNode()       {}
+func (sd *StructDeclaration) TokenLiteral() string { return sd.Token.Literal }
+func (sd *StructDeclaration) String() string {
+	fields := []string{}
+	for _, field := range sd.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return sd.TokenLiteral() + " " + sd.Name.String() + " " + braced(fields)
+}
+
+// StructField is a field of a struct declaration with a type.
+// <identifier>: <type>
+type StructField struct {
+	Name *Identifier
+	Type TypeExpr
+}
+
+func (sf *StructField) TokenLiteral() string { return sf.Name.TokenLiteral() }
+func (sf *StructField) String() string       { return sf.Name.String() + ": " + sf.Type.String() }
+
+// StructLiteral is an expression, that creates a struct with the field values.
+// <identifier> { <field>: <expression>, ... }
+type StructLiteral struct {
+	Token  token.Token // The "{" token.
+	Name   *Identifier
+	Fields []*FieldValue
+}
+
+func (sl *StructLiteral) expressionNode()      {}
+func (sl *StructLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StructLiteral) String() string {
+	fields := []string{}
+	for _, field := range sl.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return sl.Name.String() + " " + braced(fields)
+}
+
+// FieldValue is a value of a field in a struct literal.
+// <identifier>: <expression>
+type FieldValue struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (fv *FieldValue) TokenLiteral() string { return fv.Name.TokenLiteral() }
+func (fv *FieldValue) String() string       { return fv.Name.String() + ": " + fv.Value.String() }
+
+// Joins the items with commas between braces.
+func braced(items []string) string {
+	if len(items) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(items, ", ") + " }"
+}
+
+// MemberExpression is an access to a field of a struct.
+// <expression>.<identifier>
+type MemberExpression struct {
+	Token    token.Token // The "." token.
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return me.Object.String() + "." + me.Property.String()
+}
+
+// InterfaceDeclaration is a declaration of method signatures, that a type has to provide.
+// interface <identifier> { <method>: <function type>, ... }
+type InterfaceDeclaration struct {
+	Token   token.Token // The "interface" token.
+	Name    *Identifier
+	Methods []*MethodSignature
+	Doc     *CommentGroup // The doc comments before the declaration, or nil.
+}
+
+func (id *InterfaceDeclaration) statementNode()       {}
+func (id *InterfaceDeclaration) TokenLiteral() string { return id.Token.Literal }
+func (id *InterfaceDeclaration) String() string {
+	methods := []string{}
+	for _, method := range id.Methods {
+		methods = append(methods, method.String())
+	}
+
+	return id.TokenLiteral() + " " + id.Name.String() + " " + braced(methods)
+}
+
+// MethodSignature is a named method of an interface with the parameter and return types.
+// <identifier>: (<types>) <type>
+type MethodSignature struct {
+	Name *Identifier
+	Type *FunctionType
+}
+
+func (ms *MethodSignature) TokenLiteral() string { return ms.Name.TokenLiteral() }
+func (ms *MethodSignature) String() string       { return ms.Name.String() + ": " + ms.Type.String() }
+
+// ArrayLiteral is an expression, that creates an array of the elements.
+// [<expression>, ...]
+type ArrayLiteral struct {
+	Token    token.Token // The "[" token.
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, element := range al.Elements {
+		elements = append(elements, element.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// IndexExpression is an access to an element of an array.
+// <expression>[<expression>]
+type IndexExpression struct {
+	Token token.Token // The "[" token.
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// SliceExpression is an access to a range of elements of an array.
+// Both of the bounds are optional.
+// <expression>[<expression>:<expression>]
+type SliceExpression struct {
+	Token token.Token // The "[" token.
+	Left  Expression
+	Low   Expression // The first index, or nil.
+	High  Expression // The index after the last element, or nil.
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// HashLiteral is an expression, that creates a map of the key and value pairs.
+// The keys can be any expression.
+// { <expression>: <expression>, ... }
+type HashLiteral struct {
+	Token token.Token // The "{" token.
+	Pairs []*HashPair
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// HashPair is a key and value pair of a hash literal, in the order of the source.
+// <expression>: <expression>
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hp *HashPair) String() string { return hp.Key.String() + ": " + hp.Value.String() }
+
+// WhileStatement repeats the body, while the condition is true.
+// while (<condition>) { <body> }
+type WhileStatement struct {
+	Token     token.Token // The "while" token.
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(") ")
+	out.WriteString(loopBody(ws.Body))
+
+	return out.String()
+}
+
+// ForInStatement repeats the body for each element of the iterable expression.
+// for <identifier> in <expression> { <body> }
+type ForInStatement struct {
+	Token    token.Token // The "for" token.
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode()       {}
+func (fs *ForInStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(" ")
+	out.WriteString(loopBody(fs.Body))
+
+	return out.String()
+}
+
+// Returns the body of a loop between braces, so the loop can be parsed back.
+func loopBody(body *BlockStatement) string {
+	if len(body.Statements) == 0 {
+		return "{}"
+	}
+	return "{ " + body.String() + " }"
+}
+
+// BranchStatement jumps out of the loop, or to the next iteration of the loop.
+// break or continue
+type BranchStatement struct {
+	Token token.Token // The "break" or "continue" token.
+}
+
+func (bs *BranchStatement) statementNode()       {}
+func (bs *BranchStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BranchStatement) String() string       { return bs.Token.Literal + ";" }
+
+// AssignStatement assigns a new value to an identifier, an element or a field.
+// The operator is either "=" or a compound operator, like "+=".
+// <target> <operator> <expression>
+type AssignStatement struct {
+	Token  token.Token // The "=" or compound assignment token.
+	Target Expression
+	Value  Expression
+}
+
+func (as *AssignStatement) statementNode()       {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(as.Target.String())
+	out.WriteString(" " + as.Token.Literal + " ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// IncDecStatement increments or decrements the value of an identifier, an element or a field by one.
+// <target>++ or <target>--
+type IncDecStatement struct {
+	Token    token.Token // The "++" or "--" token.
+	Target   Expression
+	Operator string
+}
+
+func (ids *IncDecStatement) statementNode()       {}
+func (ids *IncDecStatement) TokenLiteral() string { return ids.Token.Literal }
+func (ids *IncDecStatement) String() string       { return ids.Target.String() + ids.Operator + ";" }
+
+// PipeExpression passes the left value as the first argument of the call on the right.
+// <expression> |> <call>
+type PipeExpression struct {
+	Token token.Token // The "|>" token.
+	Left  Expression
+	Call  *CallExpression
+}
+
+func (pe *PipeExpression) expressionNode()      {}
+func (pe *PipeExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(" |> ")
+	out.WriteString(pe.Call.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// Desugar returns the call, with the left value prepended to its arguments.
+// "xs |> map(f)" is the same call as "map(xs, f)".
+func (pe *PipeExpression) Desugar() *CallExpression {
+	arguments := append([]Expression{pe.Left}, pe.Call.Arguments...)
+	return &CallExpression{Token: pe.Call.Token, Function: pe.Call.Function, Arguments: arguments}
+}
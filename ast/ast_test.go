@@ -27,3 +27,34 @@ func TestString(t *testing.T) {
 		t.Errorf("Program didn't return a testing string. Got: %q", program.String())
 	}
 }
+
+func TestPipeExpressionDesugar(t *testing.T) {
+	identifier := func(name string) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	call := &CallExpression{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  identifier("map"),
+		Arguments: []Expression{identifier("f")},
+	}
+	pipe := &PipeExpression{
+		Token: token.Token{Type: token.PIPE, Literal: "|>"},
+		Left:  identifier("xs"),
+		Call:  call,
+	}
+
+	if pipe.String() != "(xs |> map(f))" {
+		t.Errorf("Pipe didn't return a testing string. Got: %q", pipe.String())
+	}
+
+	desugared := pipe.Desugar()
+	if desugared.String() != "map(xs, f)" {
+		t.Errorf("Desugared pipe didn't return a testing string. Got: %q", desugared.String())
+	}
+
+	// The original call is left untouched.
+	if call.String() != "map(f)" {
+		t.Errorf("Desugar changed the original call. Got: %q", call.String())
+	}
+}
@@ -1,46 +1,205 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"../token"
 )
 
+// Error describes a problem found in the input during lexing.
+// It spans from the position to the end position, such as the whole malformed number.
+type Error struct {
+	Pos token.Position
+	End token.Position
+	Msg string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s at %s", e.Msg, e.Pos)
+}
+
+// Mode is a set of flags, that control the optional behaviour of the lexer.
+type Mode uint
+
+const (
+	ScanComments Mode = 1 << iota // Emit COMMENT and DOC_COMMENT tokens instead of skipping them.
+	InsertSemis                   // Insert a SEMICOLON token at the end of lines, that can end a statement.
+)
+
 // Lexer parses through input to look for tokens.
+// The input is read incrementally, so only the current token has to fit into memory.
 type Lexer struct {
-	input        string
-	ch           byte // Current char in examination.
-	position     int  // Current position in input 			(points to current char).
-	peekPosition int  // Current peaking position in input 	(after current char).
+	reader    *bufio.Reader
+	ch        rune            // Current char in examination.
+	width     int             // Width of the current char in bytes (0 at the end of input).
+	peek      rune            // Next char after the current char.
+	peekWidth int             // Width of the next char in bytes (0 at the end of input).
+	offset    int             // Byte offset of the current char in input.
+	line      int             // Line of the current char.
+	column    int             // Column of the current char.
+	text      strings.Builder // Chars consumed since the start of the current token.
+	mode      Mode
+	errors    []Error
+
+	// Automatic semicolon insertion.
+	insertSemi bool         // Whether the last token can end a statement.
+	pending    *token.Token // Token read after an inserted semicolon.
 }
 
 // New returns a Lexer based on it's input.
 func New(input string) *Lexer {
-	lex := &Lexer{input: input}
+	return NewReader(strings.NewReader(input))
+}
+
+// NewReader returns a Lexer, that reads it's input from the reader as the tokens are needed.
+// It produces the same tokens as New would for the whole input.
+func NewReader(reader io.Reader) *Lexer {
+	lex := &Lexer{reader: bufio.NewReader(reader), line: 1}
+	lex.peek, lex.peekWidth = lex.readRune()
 	lex.readChar()
 	return lex
 }
 
+// Mode returns the flags of the optional behaviour of the lexer.
+func (lex *Lexer) Mode() Mode {
+	return lex.mode
+}
+
+// SetMode sets the flags of the optional behaviour of the lexer.
+// It should be called before reading any tokens.
+func (lex *Lexer) SetMode(mode Mode) {
+	lex.mode = mode
+}
+
+// Errors returns all the errors encountered by lexing so far.
+func (lex *Lexer) Errors() []Error {
+	return lex.errors
+}
+
+func (lex *Lexer) error(pos, end token.Position, format string, args ...interface{}) {
+	lex.errors = append(lex.errors, Error{Pos: pos, End: end, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Advances to the next char, which was already read into peek.
+// Invalid encodings are reported and read as utf8.RuneError.
 func (lex *Lexer) readChar() {
-	if lex.peekPosition >= len(lex.input) {
-		lex.ch = 0
+	// We don't advance past the end of input.
+	if lex.width == 0 && lex.column > 0 {
+		return
+	}
+
+	if lex.width > 0 {
+		lex.text.WriteRune(lex.ch)
+	}
+	if lex.ch == '\n' {
+		lex.line++
+		lex.column = 1
 	} else {
-		lex.ch = lex.input[lex.peekPosition]
+		lex.column++
+	}
+
+	lex.offset += lex.width
+	lex.ch, lex.width = lex.peek, lex.peekWidth
+	if lex.ch == utf8.RuneError && lex.width == 1 {
+		lex.error(lex.currentPosition(), lex.charEndPosition(), "invalid UTF-8 encoding")
 	}
-	lex.position = lex.peekPosition
-	lex.peekPosition++
+
+	if lex.peekWidth > 0 {
+		lex.peek, lex.peekWidth = lex.readRune()
+	}
+}
+
+// Reads the next UTF-8 encoded char from the reader.
+// At the end of input or on a read error, we return 0 with no width.
+func (lex *Lexer) readRune() (rune, int) {
+	ch, width, err := lex.reader.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			lex.error(lex.currentPosition(), lex.currentPosition(), "could not read input: %v", err)
+		}
+		return 0, 0
+	}
+	return ch, width
+}
+
+// Returns the position of the current char.
+func (lex *Lexer) currentPosition() token.Position {
+	return token.Position{Line: lex.line, Column: lex.column, Offset: lex.offset}
+}
+
+// Returns the position right after the current char, which is never a newline when it's called.
+func (lex *Lexer) charEndPosition() token.Position {
+	return token.Position{Line: lex.line, Column: lex.column + 1, Offset: lex.offset + lex.width}
 }
 
 // NextToken computes the next token based on the current char.
+// The token is annotated with its start and end position in the input.
+// Comments are skipped, unless the ScanComments mode is set.
 func (lex *Lexer) NextToken() token.Token {
+	for {
+		tok := lex.scanToken()
+		if isComment(tok.Type) && lex.mode&ScanComments == 0 {
+			continue
+		}
+		return tok
+	}
+}
+
+// Scans the next token, including comments.
+// In the InsertSemis mode, a SEMICOLON is inserted at the end of line or input,
+// if the last token on the line can end a statement.
+func (lex *Lexer) scanToken() token.Token {
+	if lex.pending != nil {
+		tok := *lex.pending
+		lex.pending = nil
+		return tok
+	}
+
+	insertSemi := lex.insertSemi && lex.mode&InsertSemis != 0
+	lex.insertSemi = false
+
+	lex.skipWhitespace(insertSemi)
+	lex.text.Reset()
+
+	start := lex.currentPosition()
+	if insertSemi && (lex.ch == '\n' || lex.ch == 0) {
+		lex.readChar()
+		return token.Token{Type: token.SEMICOLON, Literal: "\n", Start: start, End: lex.currentPosition()}
+	}
+
+	tok := lex.readToken()
+	tok.Start = start
+	tok.End = lex.currentPosition()
+
+	if isComment(tok.Type) && insertSemi {
+		// A comment running to the end of line ends the line as well.
+		if strings.HasPrefix(tok.Literal, "//") || strings.Contains(tok.Literal, "\n") {
+			lex.pending = &tok
+			return token.Token{Type: token.SEMICOLON, Literal: "\n", Start: start, End: start}
+		}
+		lex.insertSemi = true
+		return tok
+	}
+
+	lex.insertSemi = endsStatement(tok.Type)
+	return tok
+}
+
+// Reads the token starting at the current char and advances past it.
+func (lex *Lexer) readToken() token.Token {
 	var tok token.Token
 
-	lex.skipWhitespace()
 	switch lex.ch {
 	// A case for ASSIGN or EQUALS token.
 	case '=':
 		if lex.peekChar() == '=' {
-			ch := lex.ch
-			lex.readChar()
-			tok = token.Token{Type: token.EQUALS, Literal: string(ch) + string(lex.ch)}
+			tok = lex.newTwoCharToken(token.EQUALS)
 		} else {
 			tok = newToken(token.ASSIGN, lex.ch)
 		}
@@ -56,30 +215,97 @@ func (lex *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACE, lex.ch)
 	case ',':
 		tok = newToken(token.COMMA, lex.ch)
+	case ':':
+		tok = newToken(token.COLON, lex.ch)
+	case '.':
+		tok = newToken(token.DOT, lex.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, lex.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, lex.ch)
+	// The arithmetic operators can be followed by '=' as a compound assignment.
+	// The '+' and '-' can be doubled as an increment or decrement, and '*' as a power.
 	case '+':
-		tok = newToken(token.PLUS, lex.ch)
+		if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.PLUS_ASSIGN)
+		} else if lex.peekChar() == '+' {
+			tok = lex.newTwoCharToken(token.INCREMENT)
+		} else {
+			tok = newToken(token.PLUS, lex.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, lex.ch)
+		if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.MINUS_ASSIGN)
+		} else if lex.peekChar() == '-' {
+			tok = lex.newTwoCharToken(token.DECREMENT)
+		} else {
+			tok = newToken(token.MINUS, lex.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, lex.ch)
+		if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.ASTERISK_ASSIGN)
+		} else if lex.peekChar() == '*' {
+			tok = lex.newTwoCharToken(token.POWER)
+		} else {
+			tok = newToken(token.ASTERISK, lex.ch)
+		}
 	case '/':
-		tok = newToken(token.SLASH, lex.ch)
+		if lex.peekChar() == '/' {
+			return lex.readLineComment()
+		} else if lex.peekChar() == '*' {
+			return lex.readBlockComment()
+		} else if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, lex.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, lex.ch)
 	// A case of BANG or NEQUALS token.
 	case '!':
 		if lex.peekChar() == '=' {
-			ch := lex.ch
-			lex.readChar()
-			tok = token.Token{Type: token.NEQUALS, Literal: string(ch) + string(lex.ch)}
+			tok = lex.newTwoCharToken(token.NEQUALS)
 		} else {
 			tok = newToken(token.BANG, lex.ch)
 		}
+	// The STRING token keeps the quoted source text, the value is decoded by Unquote.
+	case '"':
+		if !lex.readString() {
+			return token.Token{Type: token.ILLEGAL, Literal: lex.text.String()}
+		}
+		return token.Token{Type: token.STRING, Literal: lex.text.String()}
 	case '<':
-		tok = newToken(token.LT, lex.ch)
+		if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.LTE)
+		} else {
+			tok = newToken(token.LT, lex.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, lex.ch)
+		if lex.peekChar() == '=' {
+			tok = lex.newTwoCharToken(token.GTE)
+		} else {
+			tok = newToken(token.GT, lex.ch)
+		}
+	// The logical operators have no single char form.
+	case '&':
+		if lex.peekChar() == '&' {
+			tok = lex.newTwoCharToken(token.AND)
+		} else {
+			tok = lex.newIllegalToken()
+		}
+	case '|':
+		if lex.peekChar() == '|' {
+			tok = lex.newTwoCharToken(token.OR)
+		} else if lex.peekChar() == '>' {
+			tok = lex.newTwoCharToken(token.PIPE)
+		} else {
+			tok = lex.newIllegalToken()
+		}
 	case 0:
+		// The EOF token is empty, so we don't advance past it.
 		tok.Type = token.EOF
 		tok.Literal = ""
+		return tok
 	// Default switch for all keywords, identifiers and illegal tokens.
 	default:
 		if isLetter(lex.ch) {
@@ -87,61 +313,387 @@ func (lex *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(lex.ch) {
-			tok.Literal = lex.readDigit()
-			tok.Type = token.INT
+			tok.Type, tok.Literal = lex.readNumber()
 			return tok
 		}
 		// If it's not a letter we know, we return an ILLEGAL token.
-		tok = newToken(token.ILLEGAL, lex.ch)
+		tok = lex.newIllegalToken()
 	}
 	lex.readChar()
 	return tok
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+// Reports the current char as unexpected and returns it as an ILLEGAL token.
+// Invalid encodings were already reported when they were read.
+func (lex *Lexer) newIllegalToken() token.Token {
+	if lex.ch != utf8.RuneError || lex.width != 1 {
+		lex.error(lex.currentPosition(), lex.charEndPosition(), "unexpected character %q", lex.ch)
+	}
+	return newToken(token.ILLEGAL, lex.ch)
+}
+
+// Reads the current and the next char as a single token.
+func (lex *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := lex.ch
+	lex.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(lex.ch)}
+}
+
 // Skips the whitespace in the lexing part.
-func (lex *Lexer) skipWhitespace() {
-	for lex.ch == ' ' || lex.ch == '\t' || lex.ch == '\n' || lex.ch == '\r' {
+// The newline is not skipped, if it would insert a semicolon.
+func (lex *Lexer) skipWhitespace(stopAtNewline bool) {
+	for lex.ch == ' ' || lex.ch == '\t' || lex.ch == '\n' && !stopAtNewline || lex.ch == '\r' {
+		lex.readChar()
+	}
+}
+
+// Reads the "//" comment until the end of line.
+// A comment starting with exactly three slashes is a doc comment.
+func (lex *Lexer) readLineComment() token.Token {
+	// Skip the two slashes.
+	lex.readChar()
+	lex.readChar()
+
+	tokenType := token.TokenType(token.COMMENT)
+	if lex.ch == '/' && lex.peekChar() != '/' {
+		tokenType = token.DOC_COMMENT
+	}
+
+	for lex.ch != '\n' && lex.ch != 0 {
+		lex.readChar()
+	}
+	return token.Token{Type: tokenType, Literal: lex.text.String()}
+}
+
+// Reads the "/* */" comment, which can contain nested block comments.
+func (lex *Lexer) readBlockComment() token.Token {
+	start := lex.currentPosition()
+	depth := 0
+
+	for {
+		switch {
+		case lex.ch == 0:
+			lex.error(start, lex.currentPosition(), "unterminated block comment")
+			return token.Token{Type: token.ILLEGAL, Literal: lex.text.String()}
+		case lex.ch == '/' && lex.peekChar() == '*':
+			depth++
+			lex.readChar()
+		case lex.ch == '*' && lex.peekChar() == '/':
+			depth--
+			lex.readChar()
+		}
 		lex.readChar()
+
+		if depth == 0 {
+			return token.Token{Type: token.COMMENT, Literal: lex.text.String()}
+		}
 	}
 }
 
 // Reads the identifier if it's a letter.
 func (lex *Lexer) readIdentifier() string {
-	position := lex.position
-	for isLetter(lex.ch) {
+	for isLetter(lex.ch) || unicode.IsDigit(lex.ch) {
+		lex.readChar()
+	}
+	return lex.text.String()
+}
+
+// Reads the integer or float literal if it's a digit.
+// Integers can have a base prefix (0x, 0o, 0b) and digits can be separated by '_'.
+// If the number is malformed, an error is recorded and an ILLEGAL type is returned.
+func (lex *Lexer) readNumber() (token.TokenType, string) {
+	start := lex.currentPosition()
+	tokenType := token.TokenType(token.INT)
+	problem := ""
+
+	if lex.ch == '0' && isBasePrefix(lex.peekChar()) {
+		lex.readChar()
+		prefix := lex.ch
+		lex.readChar()
+
+		isBaseDigit, name := baseDigits(prefix)
+		if !lex.readDigits(isBaseDigit) {
+			problem = "expected " + name + " digits"
+		} else if isLetter(lex.ch) || isDigit(lex.ch) {
+			problem = fmt.Sprintf("invalid character '%c' in %s literal", lex.ch, name)
+		}
+	} else {
+		valid := lex.readDigits(isDigit)
+
+		// The fraction part is read only if a digit follows the dot.
+		if lex.ch == '.' && isDigit(lex.peekChar()) {
+			tokenType = token.FLOAT
+			lex.readChar()
+			valid = lex.readDigits(isDigit) && valid
+		}
+
+		if lex.ch == 'e' || lex.ch == 'E' {
+			tokenType = token.FLOAT
+			lex.readChar()
+			if lex.ch == '+' || lex.ch == '-' {
+				lex.readChar()
+			}
+			if !isDigit(lex.ch) {
+				problem = "expected exponent digits"
+			}
+			valid = lex.readDigits(isDigit) && valid
+		}
+
+		if problem == "" && !valid {
+			problem = "'_' must separate successive digits"
+		}
+		if problem == "" && (isLetter(lex.ch) || isDigit(lex.ch)) {
+			problem = fmt.Sprintf("invalid character '%c' in number", lex.ch)
+		}
+	}
+
+	// Consume the rest of a malformed number, so it's reported as one token.
+	if problem != "" {
+		for isLetter(lex.ch) || isDigit(lex.ch) {
+			lex.readChar()
+		}
+	}
+
+	literal := lex.text.String()
+	if problem != "" {
+		lex.error(start, lex.currentPosition(), "malformed number %q: %s", literal, problem)
+		return token.ILLEGAL, literal
+	}
+	return tokenType, literal
+}
+
+// Reads a run of digits, which can be separated by single underscores.
+// Returns false if there were no digits or the underscores were misplaced.
+func (lex *Lexer) readDigits(isValidDigit func(rune) bool) bool {
+	count := 0
+	valid := true
+	previous := '_'
+
+	for isValidDigit(lex.ch) || lex.ch == '_' {
+		if lex.ch == '_' && previous == '_' {
+			valid = false
+		}
+		if lex.ch != '_' {
+			count++
+		}
+		previous = lex.ch
 		lex.readChar()
 	}
-	return lex.input[position:lex.position]
+
+	return count > 0 && valid && previous != '_'
 }
 
-// Reads the identifier if it's a digit.
-func (lex *Lexer) readDigit() string {
-	position := lex.position
-	for isDigit(lex.ch) {
+// Reads the string literal between double quotes and checks its escape sequences.
+// If the string is malformed, the errors are recorded and false is returned.
+func (lex *Lexer) readString() bool {
+	start := lex.currentPosition()
+	var chars []rune
+	var positions []token.Position
+	read := func() {
+		chars = append(chars, lex.ch)
+		positions = append(positions, lex.currentPosition())
 		lex.readChar()
 	}
-	return lex.input[position:lex.position]
+
+	// Skip the opening quote.
+	lex.readChar()
+	for lex.ch != '"' && lex.ch != 0 && lex.ch != '\n' {
+		// The escaped char is read with its backslash, so an escaped quote doesn't end the string.
+		escaped := lex.ch == '\\'
+		read()
+		if escaped && lex.ch != 0 && lex.ch != '\n' {
+			read()
+		}
+	}
+	positions = append(positions, lex.currentPosition())
+
+	_, errors := unescape(chars, positions)
+	lex.errors = append(lex.errors, errors...)
+	if lex.ch != '"' {
+		lex.error(start, lex.currentPosition(), "unterminated string")
+		return false
+	}
+
+	// Skip the closing quote.
+	lex.readChar()
+	return len(errors) == 0
+}
+
+// Unquote decodes the literal of a STRING token, which is the string in double quotes, to its value.
+// It returns the first lexer error, if the literal is not a valid string.
+func Unquote(literal string) (string, error) {
+	var chars []rune
+	var positions []token.Position
+	for offset, ch := range literal {
+		chars = append(chars, ch)
+		positions = append(positions, token.Position{Line: 1, Column: len(positions) + 1, Offset: offset})
+	}
+	positions = append(positions, token.Position{Line: 1, Column: len(positions) + 1, Offset: len(literal)})
+
+	if len(chars) == 0 || chars[0] != '"' {
+		return "", Error{Pos: positions[0], End: positions[0], Msg: "string must start with '\"'"}
+	}
+
+	// Find the closing quote, skipping the escaped chars.
+	end := 1
+	for end < len(chars) && chars[end] != '"' && chars[end] != '\n' {
+		if chars[end] == '\\' && end+1 < len(chars) && chars[end+1] != '\n' {
+			end++
+		}
+		end++
+	}
+
+	value, errors := unescape(chars[1:end], positions[1:end+1])
+	if len(errors) > 0 {
+		return "", errors[0]
+	}
+	if end == len(chars) || chars[end] != '"' {
+		return "", Error{Pos: positions[0], End: positions[end], Msg: "unterminated string"}
+	}
+	if end+1 < len(chars) {
+		return "", Error{Pos: positions[end+1], End: positions[end+1], Msg: "unexpected text after the string"}
+	}
+	return value, nil
+}
+
+// Decodes the escape sequences in the chars between the quotes of a string literal.
+// The positions hold the position of each char and the position after the last one,
+// so the malformed escape sequences are reported where they are in the input.
+func unescape(chars []rune, positions []token.Position) (string, []Error) {
+	var out strings.Builder
+	var errors []Error
+	report := func(from, to int, format string, args ...interface{}) {
+		errors = append(errors, Error{Pos: positions[from], End: positions[to], Msg: fmt.Sprintf(format, args...)})
+	}
+
+	for i := 0; i < len(chars); i++ {
+		// A backslash at the end belongs to an unterminated string, which is reported by the caller.
+		if chars[i] != '\\' || i+1 == len(chars) {
+			out.WriteRune(chars[i])
+			continue
+		}
+
+		i++
+		switch chars[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case '"':
+			out.WriteByte('"')
+		case '\\':
+			out.WriteByte('\\')
+		case 'u':
+			r, end, valid := unicodeEscape(chars, i+1)
+			if valid {
+				out.WriteRune(r)
+			} else {
+				report(i-1, end, "invalid unicode escape sequence")
+			}
+			i = end - 1
+		default:
+			report(i-1, i+1, "unknown escape sequence '\\%c'", chars[i])
+		}
+	}
+
+	return out.String(), errors
+}
+
+// Decodes the "{...}" part of a "\u{...}" escape sequence at the index, with up to 6 hex digits.
+// It returns the index after the escape, or after the char it's invalid at, if it's a closing brace.
+func unicodeEscape(chars []rune, i int) (rune, int, bool) {
+	if i == len(chars) || chars[i] != '{' {
+		return 0, i, false
+	}
+
+	end := i + 1
+	for end < len(chars) && isHexDigit(chars[end]) {
+		end++
+	}
+	if end == len(chars) || chars[end] != '}' {
+		return 0, end, false
+	}
+
+	digits := string(chars[i+1 : end])
+	if len(digits) == 0 || len(digits) > 6 {
+		return 0, end + 1, false
+	}
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || !utf8.ValidRune(rune(value)) {
+		return 0, end + 1, false
+	}
+	return rune(value), end + 1, true
+}
+
+// Helper function for declaring the token types, after which a semicolon is inserted.
+func endsStatement(tokenType token.TokenType) bool {
+	switch tokenType {
+	case token.IDENT, token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE,
+		token.RPAREN, token.RBRACE, token.RBRACKET, token.RETURN, token.BREAK, token.CONTINUE,
+		token.INCREMENT, token.DECREMENT:
+		return true
+	}
+	return false
+}
+
+// Helper function for declaring the comment token types.
+func isComment(tokenType token.TokenType) bool {
+	return tokenType == token.COMMENT || tokenType == token.DOC_COMMENT
 }
 
 // Helper function for declaring a range of letters.
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// Identifiers start with a letter and continue with letters or digits.
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-// Helper function for declaring a range of digits.
-func isDigit(ch byte) bool {
+// Helper function for declaring a range of digits in numbers.
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// Helper function for declaring the prefixes of a base in integer literals.
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// Returns the range of digits and the name of a base for its integer literal prefix.
+func baseDigits(prefix rune) (func(rune) bool, string) {
+	switch prefix {
+	case 'x', 'X':
+		return isHexDigit, "hexadecimal"
+	case 'o', 'O':
+		return isOctalDigit, "octal"
+	default:
+		return isBinaryDigit, "binary"
+	}
+}
+
+// Helper function for declaring a range of binary digits.
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
+
+// Helper function for declaring a range of octal digits.
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+// Helper function for declaring a range of hexadecimal digits.
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 // Peeks the next char at the current char.
 // If there is no such char, we return 0 (EOF).
-func (lex *Lexer) peekChar() byte {
-	if lex.peekPosition >= len(lex.input) {
-		return 0
-	}
-	return lex.input[lex.peekPosition]
+func (lex *Lexer) peekChar() rune {
+	return lex.peek
 }
@@ -1,7 +1,9 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"../token"
 )
@@ -16,7 +18,7 @@ func TestNextToken(t *testing.T) {
 
 	as result = add(x, y);
 
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -27,6 +29,11 @@ func TestNextToken(t *testing.T) {
 
 	10 == 10;
 	5 != 10;
+
+	fn first(xs: []int) of int {}
+	struct Point { x: int }
+	p.x;
+	while for in break continue interface
 	`
 	l := New(input)
 
@@ -106,6 +113,650 @@ func TestNextToken(t *testing.T) {
 		{token.NEQUALS, "!="},
 		{token.INT, "10"},
 		{token.SEMICOLON, ";"},
+		{token.FUNCTION, "fn"},
+		{token.IDENT, "first"},
+		{token.LPAREN, "("},
+		{token.IDENT, "xs"},
+		{token.COLON, ":"},
+		{token.LBRACKET, "["},
+		{token.RBRACKET, "]"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.OF, "of"},
+		{token.IDENT, "int"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.STRUCT, "struct"},
+		{token.IDENT, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "p"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.INTERFACE, "interface"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Error during iteration [%d] of checking literals. \nExpected: %q -- Got: %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// Test the start and end positions of the next tokens.
+func TestNextTokenPositions(t *testing.T) {
+	input := "as x = 10;\nx != 5;\n"
+	l := New(input)
+
+	tests := []struct {
+		expectedType  token.TokenType
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{token.DECLARE, token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 3, Offset: 2}},
+		{token.IDENT, token.Position{Line: 1, Column: 4, Offset: 3}, token.Position{Line: 1, Column: 5, Offset: 4}},
+		{token.ASSIGN, token.Position{Line: 1, Column: 6, Offset: 5}, token.Position{Line: 1, Column: 7, Offset: 6}},
+		{token.INT, token.Position{Line: 1, Column: 8, Offset: 7}, token.Position{Line: 1, Column: 10, Offset: 9}},
+		{token.SEMICOLON, token.Position{Line: 1, Column: 10, Offset: 9}, token.Position{Line: 1, Column: 11, Offset: 10}},
+		{token.IDENT, token.Position{Line: 2, Column: 1, Offset: 11}, token.Position{Line: 2, Column: 2, Offset: 12}},
+		{token.NEQUALS, token.Position{Line: 2, Column: 3, Offset: 13}, token.Position{Line: 2, Column: 5, Offset: 15}},
+		{token.INT, token.Position{Line: 2, Column: 6, Offset: 16}, token.Position{Line: 2, Column: 7, Offset: 17}},
+		{token.SEMICOLON, token.Position{Line: 2, Column: 7, Offset: 17}, token.Position{Line: 2, Column: 8, Offset: 18}},
+		{token.EOF, token.Position{Line: 3, Column: 1, Offset: 19}, token.Position{Line: 3, Column: 1, Offset: 19}},
+		{token.EOF, token.Position{Line: 3, Column: 1, Offset: 19}, token.Position{Line: 3, Column: 1, Offset: 19}},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Start != tt.expectedStart {
+			t.Fatalf("Error during iteration [%d] of checking start positions. \nExpected: %#v -- Got: %#v", i, tt.expectedStart, tok.Start)
+		}
+		if tok.End != tt.expectedEnd {
+			t.Fatalf("Error during iteration [%d] of checking end positions. \nExpected: %#v -- Got: %#v", i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+// Test the string literals with escape sequences.
+func TestNextTokenStrings(t *testing.T) {
+	input := `"Adrian Plavka" "" "a\nb\tc" "say \"hi\"" "back\\slash" "\u{48}\u{1F600}"`
+	l := New(input)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedValue   string
+	}{
+		{token.STRING, `"Adrian Plavka"`, "Adrian Plavka"},
+		{token.STRING, `""`, ""},
+		{token.STRING, `"a\nb\tc"`, "a\nb\tc"},
+		{token.STRING, `"say \"hi\""`, "say \"hi\""},
+		{token.STRING, `"back\\slash"`, "back\\slash"},
+		{token.STRING, `"\u{48}\u{1F600}"`, "H\U0001F600"},
+		{token.EOF, "", ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Error during iteration [%d] of checking literals. \nExpected: %q -- Got: %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Type != token.STRING {
+			continue
+		}
+
+		value, err := Unquote(tok.Literal)
+		if err != nil {
+			t.Fatalf("Error during iteration [%d] of unquoting: %v", i, err)
+		}
+		if value != tt.expectedValue {
+			t.Fatalf("Error during iteration [%d] of checking values. \nExpected: %q -- Got: %q", i, tt.expectedValue, value)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("Lexer had unexpected errors: %v", l.Errors())
+	}
+}
+
+// Test that malformed strings are reported as errors.
+func TestNextTokenStringErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"unterminated`, "unterminated string at 1:1"},
+		{"as x = \"broken\nline\";", "unterminated string at 1:8"},
+		{`"bad \q escape"`, "unknown escape sequence '\\q' at 1:6"},
+		{`"\u{110000}"`, "invalid unicode escape sequence at 1:2"},
+		{`"\u{}"`, "invalid unicode escape sequence at 1:2"},
+		{`"\u41"`, "invalid unicode escape sequence at 1:2"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("Expected an error for input %q. Got none.", tt.input)
+		}
+		if errors[0].Error() != tt.expectedError {
+			t.Fatalf("Expected error %q for input %q. Got: %q",
+				tt.expectedError, tt.input, errors[0].Error())
+		}
+	}
+}
+
+// Test that Unquote rejects the literals, which are not a single valid string.
+func TestUnquoteErrors(t *testing.T) {
+	tests := []struct {
+		literal       string
+		expectedError string
+	}{
+		{`abc`, "string must start with '\"' at 1:1"},
+		{`"open`, "unterminated string at 1:1"},
+		{`"bad \q"`, "unknown escape sequence '\\q' at 1:6"},
+		{`"a\"`, "unterminated string at 1:1"},
+		{`"é\u{41"`, "invalid unicode escape sequence at 1:3"},
+		{`"a" "b"`, "unexpected text after the string at 1:4"},
+	}
+
+	for _, tt := range tests {
+		_, err := Unquote(tt.literal)
+		if err == nil {
+			t.Fatalf("Expected an error for %q. Got none.", tt.literal)
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("Expected error %q for %q. Got: %q", tt.expectedError, tt.literal, err.Error())
+		}
+	}
+}
+
+// Test that the errors span the whole malformed text.
+func TestErrorSpans(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedStart token.Position
+		expectedEnd   token.Position
+	}{
+		{"x @", token.Position{Line: 1, Column: 3, Offset: 2}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{"x 0x_", token.Position{Line: 1, Column: 3, Offset: 2}, token.Position{Line: 1, Column: 6, Offset: 5}},
+		{"1__0 + 1", token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 5, Offset: 4}},
+		{`"open` + "\nx", token.Position{Line: 1, Column: 1, Offset: 0}, token.Position{Line: 1, Column: 6, Offset: 5}},
+		{`"a\qb"`, token.Position{Line: 1, Column: 3, Offset: 2}, token.Position{Line: 1, Column: 5, Offset: 4}},
+		{`"\u{110000}"`, token.Position{Line: 1, Column: 2, Offset: 1}, token.Position{Line: 1, Column: 12, Offset: 11}},
+		{`"\u41"`, token.Position{Line: 1, Column: 2, Offset: 1}, token.Position{Line: 1, Column: 4, Offset: 3}},
+		{`"\u{41"`, token.Position{Line: 1, Column: 2, Offset: 1}, token.Position{Line: 1, Column: 7, Offset: 6}},
+		{"x /* a\n/* b */", token.Position{Line: 1, Column: 3, Offset: 2}, token.Position{Line: 2, Column: 8, Offset: 14}},
+		{"é \xff", token.Position{Line: 1, Column: 3, Offset: 3}, token.Position{Line: 1, Column: 4, Offset: 4}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("Expected an error for input %q. Got none.", tt.input)
+		}
+		if errors[0].Pos != tt.expectedStart || errors[0].End != tt.expectedEnd {
+			t.Errorf("Expected the error %q for input %q to span %#v - %#v. Got: %#v - %#v",
+				errors[0].Msg, tt.input, tt.expectedStart, tt.expectedEnd, errors[0].Pos, errors[0].End)
+		}
+	}
+}
+
+// Test the integer and float literals.
+func TestNextTokenNumbers(t *testing.T) {
+	input := `0 42 007 1_000_000 0xFF 0Xff_ff 0o17 0b1010 3.14 1e-9 2.5E+3 1_0.0_1 5.abs`
+	l := New(input)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0"},
+		{token.INT, "42"},
+		{token.INT, "007"},
+		{token.INT, "1_000_000"},
+		{token.INT, "0xFF"},
+		{token.INT, "0Xff_ff"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "1e-9"},
+		{token.FLOAT, "2.5E+3"},
+		{token.FLOAT, "1_0.0_1"},
+		// A dot, which is not followed by a digit, is not a part of the number.
+		{token.INT, "5"},
+		{token.DOT, "."},
+		{token.IDENT, "abs"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Error during iteration [%d] of checking literals. \nExpected: %q -- Got: %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("Lexer had unexpected errors: %v", l.Errors())
+	}
+}
+
+// Test that malformed numbers are reported as errors.
+func TestNextTokenNumberErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"0x", `malformed number "0x": expected hexadecimal digits at 1:1`},
+		{"0b", `malformed number "0b": expected binary digits at 1:1`},
+		{"x = 1__0;", `malformed number "1__0": '_' must separate successive digits at 1:5`},
+		{"100_", `malformed number "100_": '_' must separate successive digits at 1:1`},
+		{"0b102", `malformed number "0b102": invalid character '2' in binary literal at 1:1`},
+		{"0o9", `malformed number "0o9": expected octal digits at 1:1`},
+		{"1e", `malformed number "1e": expected exponent digits at 1:1`},
+		{"1.5e+", `malformed number "1.5e+": expected exponent digits at 1:1`},
+		{"12abc", `malformed number "12abc": invalid character 'a' in number at 1:1`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("Expected one error for input %q. Got: %v", tt.input, errors)
+		}
+		if errors[0].Error() != tt.expectedError {
+			t.Fatalf("Expected error %q for input %q. Got: %q",
+				tt.expectedError, tt.input, errors[0].Error())
+		}
+	}
+}
+
+// Test the identifiers and strings containing non-ASCII chars.
+func TestNextTokenUnicode(t *testing.T) {
+	input := "as größe = \"čaj ☕\";\nπ2 + x_1 + 日本;"
+	l := New(input)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedStart   token.Position
+	}{
+		{token.DECLARE, "as", token.Position{Line: 1, Column: 1, Offset: 0}},
+		{token.IDENT, "größe", token.Position{Line: 1, Column: 4, Offset: 3}},
+		{token.ASSIGN, "=", token.Position{Line: 1, Column: 10, Offset: 11}},
+		{token.STRING, `"čaj ☕"`, token.Position{Line: 1, Column: 12, Offset: 13}},
+		{token.SEMICOLON, ";", token.Position{Line: 1, Column: 19, Offset: 23}},
+		{token.IDENT, "π2", token.Position{Line: 2, Column: 1, Offset: 25}},
+		{token.PLUS, "+", token.Position{Line: 2, Column: 4, Offset: 29}},
+		{token.IDENT, "x_1", token.Position{Line: 2, Column: 6, Offset: 31}},
+		{token.PLUS, "+", token.Position{Line: 2, Column: 10, Offset: 35}},
+		{token.IDENT, "日本", token.Position{Line: 2, Column: 12, Offset: 37}},
+		{token.SEMICOLON, ";", token.Position{Line: 2, Column: 14, Offset: 43}},
+		{token.EOF, "", token.Position{Line: 2, Column: 15, Offset: 44}},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Error during iteration [%d] of checking literals. \nExpected: %q -- Got: %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Start != tt.expectedStart {
+			t.Fatalf("Error during iteration [%d] of checking start positions. \nExpected: %#v -- Got: %#v", i, tt.expectedStart, tok.Start)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Fatalf("Lexer had unexpected errors: %v", l.Errors())
+	}
+}
+
+// Test that invalid UTF-8 encoding is reported as an error.
+func TestNextTokenInvalidUTF8(t *testing.T) {
+	l := New("x \xff y")
+
+	tests := []token.TokenType{token.IDENT, token.ILLEGAL, token.IDENT, token.EOF}
+	for i, expectedType := range tests {
+		tok := l.NextToken()
+		if tok.Type != expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, expectedType, tok.Type)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 || errors[0].Error() != "invalid UTF-8 encoding at 1:3" {
+		t.Fatalf("Expected an invalid UTF-8 encoding error. Got: %v", errors)
+	}
+}
+
+// Test that comments are skipped by default and emitted in the ScanComments mode.
+func TestNextTokenComments(t *testing.T) {
+	input := `/// Doc comment.
+	as x = 5; // Line comment.
+	/* Block /* nested */ comment */ x / 2;
+	//// Not a doc comment.
+	`
+
+	tests := []struct {
+		mode   Mode
+		tokens []token.Token
+	}{
+		{0, []token.Token{
+			{Type: token.DECLARE, Literal: "as"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.INT, Literal: "5"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.SLASH, Literal: "/"},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.EOF, Literal: ""},
+		}},
+		{ScanComments, []token.Token{
+			{Type: token.DOC_COMMENT, Literal: "/// Doc comment."},
+			{Type: token.DECLARE, Literal: "as"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.ASSIGN, Literal: "="},
+			{Type: token.INT, Literal: "5"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.COMMENT, Literal: "// Line comment."},
+			{Type: token.COMMENT, Literal: "/* Block /* nested */ comment */"},
+			{Type: token.IDENT, Literal: "x"},
+			{Type: token.SLASH, Literal: "/"},
+			{Type: token.INT, Literal: "2"},
+			{Type: token.SEMICOLON, Literal: ";"},
+			{Type: token.COMMENT, Literal: "//// Not a doc comment."},
+			{Type: token.EOF, Literal: ""},
+		}},
+	}
+
+	for _, tt := range tests {
+		l := New(input)
+		l.SetMode(tt.mode)
+
+		for i, expected := range tt.tokens {
+			tok := l.NextToken()
+			if tok.Type != expected.Type {
+				t.Fatalf("Error during iteration [%d] of checking types in mode %d. \nExpected: %q -- Got: %q", i, tt.mode, expected.Type, tok.Type)
+			}
+			if tok.Literal != expected.Literal {
+				t.Fatalf("Error during iteration [%d] of checking literals in mode %d. \nExpected: %q -- Got: %q", i, tt.mode, expected.Literal, tok.Literal)
+			}
+		}
+	}
+}
+
+// Test that an unterminated block comment is reported as an error.
+func TestNextTokenUnterminatedComment(t *testing.T) {
+	l := New("x /* outer /* inner */")
+
+	tests := []token.TokenType{token.IDENT, token.ILLEGAL, token.EOF}
+	for i, expectedType := range tests {
+		tok := l.NextToken()
+		if tok.Type != expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, expectedType, tok.Type)
+		}
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 || errors[0].Error() != "unterminated block comment at 1:3" {
+		t.Fatalf("Expected an unterminated block comment error. Got: %v", errors)
+	}
+}
+
+// Test that lexing from a reader produces the same tokens and errors as lexing a string.
+func TestNewReader(t *testing.T) {
+	input := `/// Greeting.
+	as größe = "čaj\t\u{2615}";
+	if (0x_1 <= 1_000.5e3) { ret "unterminated
+	}
+	/* block /* nested */ */ x @ 0b12 \xff;
+	`
+	// Make sure the input doesn't fit into a single buffer of the reader.
+	input = strings.Repeat(input, 500)
+
+	readers := map[string]func() *Lexer{
+		"one byte": func() *Lexer { return NewReader(iotest.OneByteReader(strings.NewReader(input))) },
+		"half":     func() *Lexer { return NewReader(iotest.HalfReader(strings.NewReader(input))) },
+		"data err": func() *Lexer { return NewReader(iotest.DataErrReader(strings.NewReader(input))) },
+	}
+
+	for name, newLexer := range readers {
+		expected := New(input)
+		expected.SetMode(ScanComments)
+		l := newLexer()
+		l.SetMode(ScanComments)
+
+		for i := 0; ; i++ {
+			expectedToken := expected.NextToken()
+			tok := l.NextToken()
+			if tok != expectedToken {
+				t.Fatalf("Error during iteration [%d] of the %s reader. \nExpected: %+v -- Got: %+v", i, name, expectedToken, tok)
+			}
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+
+		if len(l.Errors()) != len(expected.Errors()) {
+			t.Fatalf("Expected %d errors for the %s reader. Got: %d", len(expected.Errors()), name, len(l.Errors()))
+		}
+		for i, err := range l.Errors() {
+			if err != expected.Errors()[i] {
+				t.Fatalf("Error [%d] of the %s reader doesn't match. \nExpected: %v -- Got: %v", i, name, expected.Errors()[i], err)
+			}
+		}
+	}
+}
+
+// Test that a failing reader is reported as an error and ends the input.
+func TestNewReaderError(t *testing.T) {
+	reader := iotest.TimeoutReader(iotest.OneByteReader(strings.NewReader("x + y")))
+	l := NewReader(reader)
+
+	tests := []token.TokenType{token.IDENT, token.EOF, token.EOF}
+	for i, expectedType := range tests {
+		tok := l.NextToken()
+		if tok.Type != expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, expectedType, tok.Type)
+		}
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Msg, iotest.ErrTimeout.Error()) {
+		t.Fatalf("Expected a read error. Got: %v", errs)
+	}
+}
+
+// Test the multi-char and compound assignment operators.
+func TestNextTokenOperators(t *testing.T) {
+	input := `a <= b >= c % d && e || f; x += 1; x -= 1; x *= 2; x /= 2; x++; x--; a ** b; xs |> f(); & |`
+	l := New(input)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LTE, "<="},
+		{token.IDENT, "b"},
+		{token.GTE, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.AND, "&&"},
+		{token.IDENT, "e"},
+		{token.OR, "||"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.INCREMENT, "++"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.DECREMENT, "--"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.POWER, "**"},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.ILLEGAL, "&"},
+		{token.ILLEGAL, "|"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("Error during iteration [%d] of checking types. \nExpected: %q -- Got: %q", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("Error during iteration [%d] of checking literals. \nExpected: %q -- Got: %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// Test that unexpected chars are reported as errors.
+func TestNextTokenIllegalErrors(t *testing.T) {
+	input := "as x = 5;\nas y = x @ 2 # &3;"
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	expectedErrors := []string{
+		"unexpected character '@' at 2:10",
+		"unexpected character '#' at 2:14",
+		"unexpected character '&' at 2:16",
+	}
+
+	errors := l.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors. Got: %v", len(expectedErrors), errors)
+	}
+	for i, expected := range expectedErrors {
+		if errors[i].Error() != expected {
+			t.Errorf("Expected error %q. Got: %q", expected, errors[i].Error())
+		}
+	}
+}
+
+// Test the automatic semicolon insertion at the end of lines.
+func TestNextTokenInsertSemis(t *testing.T) {
+	input := `as x = 5
+	as s = "a" // Comment.
+	add(x, 1) /* Block
+	comment */ if (x) {
+		ret
+	}
+	x +
+	y /* Inline */
+	x;
+	i++
+	z`
+
+	l := New(input)
+	l.SetMode(InsertSemis)
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.DECLARE, "as"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, "\n"},
+		{token.DECLARE, "as"},
+		{token.IDENT, "s"},
+		{token.ASSIGN, "="},
+		{token.STRING, `"a"`},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "add"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.INT, "1"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, "\n"},
+		{token.IF, "if"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RETURN, "ret"},
+		{token.SEMICOLON, "\n"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "i"},
+		{token.INCREMENT, "++"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "z"},
+		{token.SEMICOLON, "\n"},
+		{token.EOF, ""},
 	}
 
 	for i, tt := range tests {
@@ -1,13 +1,133 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"text/tabwriter"
 
+	"./lexer"
 	"./repl"
+	"./token"
 )
 
+const usage = `Usage:
+  ae                         Start the REPL.
+  ae tokens [flags] <file>   Print the tokens of the file ("-" reads stdin).
+`
+
 func main() {
-	fmt.Println("REPL for Ae programming language.\n")
+	if len(os.Args) > 1 && os.Args[1] == "tokens" {
+		os.Exit(tokens(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	fmt.Println("REPL for Ae programming language.")
+	fmt.Println()
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// jsonToken is a token, as it's printed by "ae tokens -json".
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Start   jsonPosition    `json:"start"`
+	End     jsonPosition    `json:"end"`
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+func newJSONPosition(pos token.Position) jsonPosition {
+	return jsonPosition{Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+// Runs the "tokens" command, which prints the token stream of a file.
+// Tokens are printed as an aligned table, or as JSON lines with the "-json" flag.
+// Lexer errors are printed to stderr and result in the exit code 1, the same as failing to write the output.
+func tokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	asJSON := flags.Bool("json", false, "print the tokens as JSON lines")
+	comments := flags.Bool("comments", false, "include the comment tokens")
+	semis := flags.Bool("semis", false, "insert semicolons at the end of lines")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	input := stdin
+	if name := flags.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	lex := lexer.NewReader(input)
+	if *comments {
+		lex.SetMode(lex.Mode() | lexer.ScanComments)
+	}
+	if *semis {
+		lex.SetMode(lex.Mode() | lexer.InsertSemis)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		for tok := lex.NextToken(); ; tok = lex.NextToken() {
+			err := encoder.Encode(jsonToken{
+				Type:    tok.Type,
+				Literal: tok.Literal,
+				Start:   newJSONPosition(tok.Start),
+				End:     newJSONPosition(tok.End),
+			})
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+	} else {
+		writer := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "POSITION\tTYPE\tLITERAL")
+		for tok := lex.NextToken(); ; tok = lex.NextToken() {
+			fmt.Fprintf(writer, "%s-%s\t%s\t%q\n", tok.Start, tok.End, tok.Type, tok.Literal)
+			if tok.Type == token.EOF {
+				break
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	for _, err := range lex.Errors() {
+		fmt.Fprintln(stderr, err)
+	}
+	if len(lex.Errors()) > 0 {
+		return 1
+	}
+	return 0
+}
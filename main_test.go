@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const tokensInput = "as x = 5; // c\nx"
+
+// Runs the "tokens" command with the input on stdin.
+func runTokens(args []string, input string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := tokens(args, strings.NewReader(input), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestTokensTable(t *testing.T) {
+	code, stdout, stderr := runTokens([]string{"-"}, tokensInput)
+
+	expected := `POSITION  TYPE     LITERAL
+1:1-1:3   DECLARE  "as"
+1:4-1:5   IDENT    "x"
+1:6-1:7   =        "="
+1:8-1:9   INT      "5"
+1:9-1:10  ;        ";"
+2:1-2:2   IDENT    "x"
+2:2-2:2   EOF      ""
+`
+	if code != 0 {
+		t.Errorf("Expected exit code 0. Got: %d, stderr: %q", code, stderr)
+	}
+	if stdout != expected {
+		t.Errorf("Expected output:\n%s\nGot:\n%s", expected, stdout)
+	}
+}
+
+func TestTokensJSON(t *testing.T) {
+	code, stdout, stderr := runTokens([]string{"-json", "-"}, tokensInput)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0. Got: %d, stderr: %q", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected 7 JSON lines. Got: %d", len(lines))
+	}
+
+	expected := `{"type":"IDENT","literal":"x","start":{"line":2,"column":1,"offset":15},"end":{"line":2,"column":2,"offset":16}}`
+	if lines[5] != expected {
+		t.Errorf("Expected line %q. Got: %q", expected, lines[5])
+	}
+
+	for _, line := range lines {
+		var tok jsonToken
+		if err := json.Unmarshal([]byte(line), &tok); err != nil {
+			t.Fatalf("Could not decode the line %q: %v", line, err)
+		}
+	}
+}
+
+func TestTokensFlags(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+		present  bool
+	}{
+		{[]string{"-"}, `COMMENT  "// c"`, false},
+		{[]string{"-comments", "-"}, `COMMENT  "// c"`, true},
+		{[]string{"-"}, `;        "\n"`, false},
+		{[]string{"-semis", "-"}, `;        "\n"`, true},
+	}
+
+	for _, tt := range tests {
+		code, stdout, stderr := runTokens(tt.args, tokensInput)
+		if code != 0 {
+			t.Fatalf("Expected exit code 0 for %q. Got: %d, stderr: %q", tt.args, code, stderr)
+		}
+		if strings.Contains(stdout, tt.expected) != tt.present {
+			t.Errorf("Expected %q to be printed for %q: %t. Got:\n%s",
+				tt.expected, tt.args, tt.present, stdout)
+		}
+	}
+}
+
+func TestTokensExitCodes(t *testing.T) {
+	tests := []struct {
+		args           []string
+		input          string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{[]string{"-"}, "as x = 5;", 0, ""},
+		{[]string{"-"}, "x @ 5", 1, "unexpected character '@' at 1:3\n"},
+		{[]string{"-json", "-"}, `"open`, 1, "unterminated string at 1:1\n"},
+		{[]string{"does-not-exist.ae"}, "", 1, "open does-not-exist.ae"},
+		{[]string{}, "", 2, "Usage:"},
+		{[]string{"a.ae", "b.ae"}, "", 2, "Usage:"},
+		{[]string{"-unknown", "-"}, "", 2, "flag provided but not defined: -unknown"},
+	}
+
+	for _, tt := range tests {
+		code, _, stderr := runTokens(tt.args, tt.input)
+		if code != tt.expectedCode {
+			t.Errorf("Expected exit code %d for %q. Got: %d", tt.expectedCode, tt.args, code)
+		}
+		if (tt.expectedStderr == "" && stderr != "") || !strings.HasPrefix(stderr, tt.expectedStderr) {
+			t.Errorf("Expected stderr %q for %q. Got: %q", tt.expectedStderr, tt.args, stderr)
+		}
+	}
+}
+
+// errorWriter fails every write.
+type errorWriter struct{}
+
+func (errorWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTokensWriteError(t *testing.T) {
+	for _, args := range [][]string{{"-"}, {"-json", "-"}} {
+		var stderr bytes.Buffer
+		code := tokens(args, strings.NewReader(tokensInput), errorWriter{}, &stderr)
+
+		if code != 1 {
+			t.Errorf("Expected exit code 1 for %q. Got: %d", args, code)
+		}
+		if stderr.String() != "disk full\n" {
+			t.Errorf("Expected the write error for %q. Got: %q", args, stderr.String())
+		}
+	}
+}
+
+// String tokens are printed with their source text, not the decoded value.
+func TestTokensStringSource(t *testing.T) {
+	code, stdout, stderr := runTokens([]string{"-"}, `"a\tb"`)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0. Got: %d, stderr: %q", code, stderr)
+	}
+
+	expected := `1:1-1:7   STRING  "\"a\\tb\""`
+	if !strings.Contains(stdout, expected) {
+		t.Errorf("Expected %q to be printed. Got:\n%s", expected, stdout)
+	}
+}
@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+
+	"../lexer"
+	"../token"
+)
+
+// Severity tells, how serious a diagnostic is.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Code identifies the kind of a diagnostic.
+// Codes are stable, so that tooling can filter on them, even when the messages change.
+type Code string
+
+const (
+	LexicalError      Code = "lexical-error"       // Reported by the lexer.
+	UnexpectedToken   Code = "unexpected-token"    // A different token was expected.
+	MissingExpression Code = "missing-expression"  // The token can't start an expression.
+	InvalidNumber     Code = "invalid-number"      // The number literal is out of range.
+	InvalidString     Code = "invalid-string"      // The string literal can't be decoded.
+	MissingType       Code = "missing-type"        // The token can't start a type.
+	BranchOutsideLoop Code = "branch-outside-loop" // A break or continue is not inside of a loop.
+	InvalidAssignment Code = "invalid-assignment"  // The left side of an assignment can't be assigned to.
+	InvalidPipe       Code = "invalid-pipe"        // The right side of a pipeline is not a call.
+)
+
+// Diagnostic is a problem found in the source, spanning from the start to the end position.
+type Diagnostic struct {
+	Start    token.Position
+	End      token.Position
+	Severity Severity
+	Code     Code
+	Message  string
+	Hint     string // Optional suggestion, how to fix the problem.
+}
+
+// String returns the message with the start position, the same way as lexer errors are printed.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s at %s", d.Message, d.Start)
+}
+
+// Converts the lexer error to a diagnostic.
+func lexerDiagnostic(err lexer.Error) Diagnostic {
+	return Diagnostic{
+		Start:    err.Pos,
+		End:      err.End,
+		Severity: SeverityError,
+		Code:     LexicalError,
+		Message:  err.Msg,
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"../ast"
 	"../lexer"
@@ -19,51 +20,117 @@ type (
 const (
 	_ int = iota
 	LOWEST
+	PIPE        // |>
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
-	LESSGREATER // < or >
+	LESSGREATER // <, >, <= or >=
 	SUM         // +
-	PRODUCT     // *
-	PREFIX      // -- or ++
+	PRODUCT     // *, / or %
+	PREFIX      // -x or !x
+	POWER       // **
 	CALL        // fn()
+	INDEX       // xs[0]
+	MEMBER      // p.x
 )
 
 var precedences = map[token.TokenType]int{
+	token.PIPE:     PIPE,
+	token.OR:       LOGICAL_OR,
+	token.AND:      LOGICAL_AND,
 	token.EQUALS:   EQUALS,
 	token.NEQUALS:  EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LTE:      LESSGREATER,
+	token.GTE:      LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
+	token.LPAREN:   CALL,
+	token.LBRACKET: INDEX,
+	token.DOT:      MEMBER,
+}
+
+// Infix operators, which group to the right, so "a ** b ** c" is parsed as "a ** (b ** c)".
+var rightAssociative = map[token.TokenType]bool{
+	token.POWER: true,
+}
+
+// Tokens, which assign a value to the left side of an expression statement.
+var assignOperators = map[token.TokenType]bool{
+	token.ASSIGN:          true,
+	token.PLUS_ASSIGN:     true,
+	token.MINUS_ASSIGN:    true,
+	token.ASTERISK_ASSIGN: true,
+	token.SLASH_ASSIGN:    true,
+}
+
+// Tokens, which start a statement.
+// After an error, the parser skips the tokens until one of them, to continue with the next statement.
+var statementKeywords = map[token.TokenType]bool{
+	token.DECLARE:   true,
+	token.RETURN:    true,
+	token.STRUCT:    true,
+	token.INTERFACE: true,
+	token.WHILE:     true,
+	token.FOR:       true,
+	token.BREAK:     true,
+	token.CONTINUE:  true,
+}
+
+// Names of the built-in types, which are parsed as a SimpleType.
+var simpleTypes = map[string]bool{
+	"int":    true,
+	"float":  true,
+	"string": true,
+	"bool":   true,
 }
 
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l         *lexer.Lexer
+	errors    []Diagnostic
+	panicking bool // Whether the current statement has an error. No more errors are reported, until it's skipped.
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// Doc comments right before the current and peek token.
+	curDoc  *ast.CommentGroup
+	peekDoc *ast.CommentGroup
+
+	braceDepth      int  // Number of '{' tokens, which are not closed by the current token.
+	loopDepth       int  // Number of loops around the current token, inside of the current function.
+	noStructLiteral bool // Whether an identifier followed by '{' is not a struct literal, as in a for-in header.
+
 	// Parse functions for expressions.
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 // New expects a lexer and returns a Parser struct.
+// Doc comments are attached to declarations only if the lexer is in the ScanComments mode.
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []Diagnostic{}}
 
 	// Register the prefix parse functions.
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
 	// Register the infix parse functions.
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -71,10 +138,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LTE, p.parseInfixExpression)
+	p.registerInfix(token.GTE, p.parseInfixExpression)
 	p.registerInfix(token.EQUALS, p.parseInfixExpression)
 	p.registerInfix(token.NEQUALS, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	p.prepareTokens()
 	return p
@@ -89,8 +166,34 @@ func (p *Parser) prepareTokens() {
 }
 
 // Errors returns all the errors encountered by parsing.
-func (p *Parser) Errors() []string {
-	return p.errors
+// The errors of the lexer come first, as they are often the cause of the parse errors.
+func (p *Parser) Errors() []Diagnostic {
+	errors := []Diagnostic{}
+	for _, err := range p.l.Errors() {
+		errors = append(errors, lexerDiagnostic(err))
+	}
+	return append(errors, p.errors...)
+}
+
+// Reports an error spanning the token.
+func (p *Parser) error(tok token.Token, code Code, format string, args ...interface{}) {
+	p.report(Diagnostic{
+		Start:    tok.Start,
+		End:      tok.End,
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Records the diagnostic, unless the current statement already has an error.
+// Only the first error of a statement is reported, as the following ones are usually caused by it.
+func (p *Parser) report(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, d)
 }
 
 func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
@@ -102,25 +205,51 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("Expected next token to be %s. Got: %s",
+	p.error(p.peekToken, UnexpectedToken, "Expected next token to be %s. Got: %s",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
 }
 
 // Set the tokens to point to the current and the next token.
+// Comments are skipped, but doc comments are kept for the token after them.
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	p.peekToken = p.l.NextToken()
+	p.curDoc = p.peekDoc
+	p.peekDoc = nil
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		p.braceDepth--
+	}
+
+	for {
+		p.peekToken = p.l.NextToken()
+
+		switch p.peekToken.Type {
+		case token.COMMENT:
+			continue
+		case token.DOC_COMMENT:
+			if p.peekDoc == nil {
+				p.peekDoc = &ast.CommentGroup{}
+			}
+			p.peekDoc.List = append(p.peekDoc.List, p.peekToken)
+			continue
+		}
+		return
+	}
 }
 
 // Parse parses the lexer tokens and returns a Program.
+// Statements with errors are left out of the program, but the parsing continues after them,
+// so that all of the errors are reported in one pass.
 func (p *Parser) Parse() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
 	// Loop until hit by an EOF token.
 	for !p.curTokenIs(token.EOF) {
-		statement := p.parseStatement()
+		statement := p.parseStatementOrSkip()
 		if statement != nil {
 			program.Statements = append(program.Statements, statement)
 		}
@@ -129,25 +258,86 @@ func (p *Parser) Parse() *ast.Program {
 	return program
 }
 
-// Parses the statement tokens DECLARE and RETURN.
-// These are the two only statements in the language.
+// Parses the statements by their leading token, such as DECLARE or RETURN.
+// A lone semicolon is an empty statement, which is skipped.
+// The '{' token in a statement position always starts a block, in an expression position it starts a hash literal.
 //
 // If it is not a statement, we parse it as an ExpressionStatement.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
+	case token.SEMICOLON:
+		return nil
+	case token.LBRACE:
+		return p.parseBlockStatement()
 	case token.DECLARE:
 		return p.parseAsStatement()
 	case token.RETURN:
 		return p.parseRetStatement()
+	case token.STRUCT:
+		return p.parseStructDeclaration()
+	case token.INTERFACE:
+		return p.parseInterfaceDeclaration()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForInStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseBranchStatement()
+	case token.FUNCTION:
+		// A function with a name is a declaration, otherwise it's a function literal.
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionDeclaration()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
+// Parses the statement, or skips the rest of it, if it has errors.
+// The statement with errors is dropped, so it's never mistaken for a valid one.
+func (p *Parser) parseStatementOrSkip() ast.Statement {
+	// The braces around the statement, without the '{' token, that may start it.
+	depth := p.braceDepth
+	if p.curTokenIs(token.LBRACE) {
+		depth--
+	}
+
+	statement := p.parseStatement()
+	if !p.panicking {
+		return statement
+	}
+
+	p.skipStatement(depth)
+	p.panicking = false
+	return nil
+}
+
+// Skips the tokens until the end of the current statement, which started inside of the given brace depth.
+// The braces opened inside of the statement are skipped as a whole. Outside of them, it stops at
+// the ';' or '}' token, or before the '}' token or the token, that starts a new statement.
+// The current token is always left at the last token of the statement, so the caller can
+// move to the next statement as usual.
+func (p *Parser) skipStatement(depth int) {
+	for !p.curTokenIs(token.EOF) {
+		if p.braceDepth <= depth {
+			if p.curTokenIs(token.SEMICOLON) || p.curTokenIs(token.RBRACE) {
+				return
+			}
+			if p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) || statementKeywords[p.peekToken.Type] {
+				return
+			}
+		}
+		p.nextToken()
+	}
+}
+
 // Parses the 'as' statement.
 // It has to contain an identifier & assign tokens and an expression.
-func (p *Parser) parseAsStatement() *ast.DeclareStatement {
-	statement := &ast.DeclareStatement{Token: p.curToken}
+// The identifier can be followed by a type, in which case the assign token and expression are optional.
+// The semicolon at the end is optional.
+func (p *Parser) parseAsStatement() ast.Statement {
+	statement := &ast.DeclareStatement{Token: p.curToken, Doc: p.curDoc}
 
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -155,12 +345,31 @@ func (p *Parser) parseAsStatement() *ast.DeclareStatement {
 
 	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		statement.Type = p.parseType()
+		if statement.Type == nil {
+			return nil
+		}
+
+		// The declaration without a value ends here.
+		if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+			if p.peekTokenIs(token.SEMICOLON) {
+				p.nextToken()
+			}
+			return statement
+		}
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	// TODO: Skipping expressions for now.
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -185,28 +394,39 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	return false
 }
 
-// Parses the 'rt' statement.
-// It has to contain an expression.
+// Parses the 'ret' statement.
+// It can contain an expression, unless it's followed by a semicolon, '}' or the end of input.
+// The semicolon at the end is optional.
 func (p *Parser) parseRetStatement() *ast.ReturnStatement {
 	statement := &ast.ReturnStatement{Token: p.curToken}
 
-	p.nextToken()
+	if !p.peekTokenIs(token.SEMICOLON) && !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+		statement.Value = p.parseExpression(LOWEST)
+	}
 
-	// TODO: Skipping expressions for now.
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
 	return statement
 }
 
-// Parses the ExpressionStatement.
-// It follows a precedence order (LOWEST, LESSGREATER, ...).
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+// Parses the ExpressionStatement, starting with the LOWEST precedence.
+// When the expression is followed by an assignment operator, it's parsed as an assignment instead,
+// and when it's followed by "++" or "--", as an increment or decrement.
+func (p *Parser) parseExpressionStatement() ast.Statement {
 	statement := &ast.ExpressionStatement{Token: p.curToken}
 
 	statement.Expression = p.parseExpression(LOWEST)
 
+	if assignOperators[p.peekToken.Type] {
+		return p.parseAssignStatement(statement.Token, statement.Expression)
+	}
+	if p.peekTokenIs(token.INCREMENT) || p.peekTokenIs(token.DECREMENT) {
+		return p.parseIncDecStatement(statement.Token, statement.Expression)
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -217,7 +437,7 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
-		p.noPrefixParserError(p.curToken.Type)
+		p.noPrefixParserError(p.curToken)
 		return nil
 	}
 	leftExpression := prefix()
@@ -235,23 +455,35 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExpression
 }
 
-func (p *Parser) noPrefixParserError(t token.TokenType) {
-	msg := fmt.Sprintf("No prefix parse function for %s found",
-		t)
-	p.errors = append(p.errors, msg)
+func (p *Parser) noPrefixParserError(t token.Token) {
+	// The lexer already reported, why the token is illegal,
+	// but the statement still has an error and has to be skipped.
+	if t.Type == token.ILLEGAL {
+		p.panicking = true
+		return
+	}
+	p.error(t, MissingExpression, "No prefix parse function for %s found",
+		t.Type)
 }
 
+// Parses the identifier, or a struct literal if the identifier is followed by '{'.
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.LBRACE) && !p.noStructLiteral {
+		return p.parseStructLiteral(identifier)
+	}
+
+	return identifier
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	literal := &ast.IntegerLiteral{Token: p.curToken}
 
-	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	digits, base := integerBase(strings.Replace(p.curToken.Literal, "_", "", -1))
+	val, err := strconv.ParseInt(digits, base, 64)
 	if err != nil {
-		msg := fmt.Sprintf("Could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.error(p.curToken, InvalidNumber, "Could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -259,6 +491,50 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return literal
 }
 
+// Splits the base prefix from the integer literal digits.
+// Literals without a prefix are always decimal, even with leading zeros.
+func integerBase(literal string) (string, int) {
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			return literal[2:], 16
+		case 'o', 'O':
+			return literal[2:], 8
+		case 'b', 'B':
+			return literal[2:], 2
+		}
+	}
+	return literal, 10
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	literal := &ast.FloatLiteral{Token: p.curToken}
+
+	val, err := strconv.ParseFloat(strings.Replace(p.curToken.Literal, "_", "", -1), 64)
+	if err != nil {
+		p.error(p.curToken, InvalidNumber, "Could not parse %q as float", p.curToken.Literal)
+		return nil
+	}
+
+	literal.Value = val
+	return literal
+}
+
+// Parses the string literal, whose token holds the quoted source text.
+func (p *Parser) parseStringLiteral() ast.Expression {
+	value, err := lexer.Unquote(p.curToken.Literal)
+	// The lexer turns malformed strings into ILLEGAL tokens, so this only guards hand-built tokens.
+	if err != nil {
+		p.error(p.curToken, InvalidString, "Could not parse %s as string: %v", p.curToken.Literal, err)
+		return nil
+	}
+
+	return &ast.StringLiteral{Token: p.curToken, Value: value}
+}
+
+// Parses the prefix operator with its operand.
+// The operand includes the power operator, so "-a ** b" is parsed as "-(a ** b)", as in math,
+// but the other infix operators are applied after the prefix operator, as in "(-a) * b".
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -288,7 +564,10 @@ func (p *Parser) curPrecedence() int {
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = false
 	exp := p.parseExpression(LOWEST)
+	p.noStructLiteral = noStructLiteral
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -297,6 +576,8 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 	return exp
 }
 
+// Parses the right side of the infix operator with the operator's precedence.
+// For right associative operators, the precedence is lowered, so the right side takes the same operator.
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	expression := &ast.InfixExpression{
 		Token:    p.curToken,
@@ -304,6 +585,25 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		Operator: p.curToken.Literal,
 	}
 
+	precedence := p.curPrecedence()
+	if rightAssociative[p.curToken.Type] {
+		precedence--
+	}
+	p.nextToken()
+
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+// Parses the "&&" or "||" operator, which short-circuits, as a LogicalExpression.
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Operator: p.curToken.Literal,
+	}
+
 	precedence := p.curPrecedence()
 	p.nextToken()
 
@@ -312,6 +612,37 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// Parses the pipeline "<expression> |> <call>", where the right side has to be a call.
+// The pipelines are grouped to the left, so "xs |> f() |> g()" is parsed as "(xs |> f()) |> g()".
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	expression := &ast.PipeExpression{Token: p.curToken, Left: left}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+
+	first := p.curToken
+	right := p.parseExpression(precedence)
+	if right == nil {
+		return nil
+	}
+
+	call, ok := right.(*ast.CallExpression)
+	if !ok {
+		p.report(Diagnostic{
+			Start:    first.Start,
+			End:      p.curToken.End,
+			Severity: SeverityError,
+			Code:     InvalidPipe,
+			Message:  fmt.Sprintf("Expected a call after %s. Got: %s", token.PIPE, right),
+			Hint:     "The value is passed as the first argument of a call, as in \"xs |> map(f)\".",
+		})
+		return nil
+	}
+
+	expression.Call = call
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -349,19 +680,646 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// Parses the statements between the '{' and '}' tokens.
+// Blocks are parsed after the keywords, that expect them, or for '{' in a statement position.
+// Everywhere else, '{' starts a hash literal.
+//
+// A block, which isn't closed before the end of input, is reported as an error.
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	depth := p.braceDepth
 
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) {
-		statement := p.parseStatement()
+		if p.curTokenIs(token.EOF) {
+			p.report(Diagnostic{
+				Start:    p.curToken.Start,
+				End:      p.curToken.End,
+				Severity: SeverityError,
+				Code:     UnexpectedToken,
+				Message:  fmt.Sprintf("Expected next token to be %s. Got: %s", token.RBRACE, token.EOF),
+				Hint:     fmt.Sprintf("The block at %s is not closed.", block.Token.Start),
+			})
+			return block
+		}
+
+		statement := p.parseStatementOrSkip()
 		if statement != nil {
 			block.Statements = append(block.Statements, statement)
 		}
+
+		// The statement with errors may have ended at the '}' token, which closes the block.
+		if p.braceDepth < depth {
+			break
+		}
 		p.nextToken()
 	}
 
 	return block
 }
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	literal := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	literal.Parameters = p.parseFunctionParameters()
+	if literal.Parameters == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	literal.Body = p.parseFunctionBody()
+
+	return literal
+}
+
+// Parses the comma separated identifiers of function parameters until the ')' token.
+// A trailing comma before the ')' token is allowed.
+func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+	identifiers := []*ast.Identifier{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		identifiers = append(identifiers, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return identifiers
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	expression := &ast.CallExpression{Token: p.curToken, Function: function}
+
+	expression.Arguments = p.parseExpressionList(token.RPAREN)
+	if expression.Arguments == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// Parses the comma separated expressions until the end token.
+// A trailing comma before the end token is allowed.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = false
+	defer func() { p.noStructLiteral = noStructLiteral }()
+
+	for !p.peekTokenIs(end) {
+		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
+
+		if !p.peekTokenIs(end) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return list
+}
+
+// Parses the named function declaration.
+// The parameters have to be typed and the return type after 'of' is optional.
+func (p *Parser) parseFunctionDeclaration() ast.Statement {
+	declaration := &ast.FunctionDeclaration{Token: p.curToken, Doc: p.curDoc}
+
+	p.nextToken()
+	declaration.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	declaration.Parameters = p.parseTypedParameters()
+	if declaration.Parameters == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.OF) {
+		p.nextToken()
+		p.nextToken()
+		declaration.ReturnType = p.parseType()
+		if declaration.ReturnType == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	declaration.Body = p.parseFunctionBody()
+
+	return declaration
+}
+
+// Parses the comma separated parameters with types until the ')' token.
+// A trailing comma before the ')' token is allowed.
+func (p *Parser) parseTypedParameters() []*ast.Parameter {
+	params := []*ast.Parameter{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		param := &ast.Parameter{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		param.Type = p.parseType()
+		if param.Type == nil {
+			return nil
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return params
+}
+
+// Parses the type annotation starting at the current token.
+func (p *Parser) parseType() ast.TypeExpr {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if simpleTypes[p.curToken.Literal] {
+			return &ast.SimpleType{Token: p.curToken, Name: p.curToken.Literal}
+		}
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayType()
+	case token.LPAREN:
+		return p.parseFunctionType()
+	}
+
+	p.error(p.curToken, MissingType, "Expected a type. Got: %s", p.curToken.Type)
+	return nil
+}
+
+func (p *Parser) parseArrayType() ast.TypeExpr {
+	arrayType := &ast.ArrayType{Token: p.curToken}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	p.nextToken()
+	arrayType.Element = p.parseType()
+	if arrayType.Element == nil {
+		return nil
+	}
+
+	return arrayType
+}
+
+// Parses the function type with comma separated parameter types.
+// The return type is parsed, if a type follows the ')' token.
+func (p *Parser) parseFunctionType() ast.TypeExpr {
+	functionType := &ast.FunctionType{Token: p.curToken, Parameters: []ast.TypeExpr{}}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		param := p.parseType()
+		if param == nil {
+			return nil
+		}
+		functionType.Parameters = append(functionType.Parameters, param)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.IDENT) || p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		functionType.Return = p.parseType()
+		if functionType.Return == nil {
+			return nil
+		}
+	}
+
+	return functionType
+}
+
+// Parses the struct declaration with typed fields.
+// The fields are separated by commas or semicolons, which can also trail the last field.
+func (p *Parser) parseStructDeclaration() ast.Statement {
+	declaration := &ast.StructDeclaration{Token: p.curToken, Doc: p.curDoc}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	declaration.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	declaration.Fields = []*ast.StructField{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.StructField{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		field.Type = p.parseType()
+		if field.Type == nil {
+			return nil
+		}
+		declaration.Fields = append(declaration.Fields, field)
+
+		if !p.expectMemberSeparator() {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return declaration
+}
+
+// Parses the interface declaration with method signatures.
+// The methods are separated by commas or semicolons, which can also trail the last method.
+func (p *Parser) parseInterfaceDeclaration() ast.Statement {
+	declaration := &ast.InterfaceDeclaration{Token: p.curToken, Doc: p.curDoc}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	declaration.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	declaration.Methods = []*ast.MethodSignature{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		method := &ast.MethodSignature{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if !p.expectPeek(token.COLON) || !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+
+		methodType := p.parseFunctionType()
+		if methodType == nil {
+			return nil
+		}
+		method.Type = methodType.(*ast.FunctionType)
+		declaration.Methods = append(declaration.Methods, method)
+
+		if !p.expectMemberSeparator() {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return declaration
+}
+
+// Expects a comma or a semicolon after a member of a declaration, unless the '}' token follows.
+func (p *Parser) expectMemberSeparator() bool {
+	if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		return true
+	}
+	if p.peekTokenIs(token.RBRACE) {
+		return true
+	}
+
+	p.peekError(token.COMMA)
+	return false
+}
+
+// Parses the struct literal with comma separated field values.
+// A trailing comma before the '}' token is allowed.
+func (p *Parser) parseStructLiteral(name *ast.Identifier) ast.Expression {
+	p.nextToken()
+	literal := &ast.StructLiteral{Token: p.curToken, Name: name, Fields: []*ast.FieldValue{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.FieldValue{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		field.Value = p.parseExpression(LOWEST)
+		if field.Value == nil {
+			return nil
+		}
+		literal.Fields = append(literal.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return literal
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	expression := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	expression.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return expression
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+
+	return array
+}
+
+// Parses the index expression, or a slice expression if there is a ':' token inside the brackets.
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	startToken := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice := &ast.SliceExpression{Token: startToken, Left: left, Low: index}
+
+		if !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			slice.High = p.parseExpression(LOWEST)
+		}
+
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+
+		return slice
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return &ast.IndexExpression{Token: startToken, Left: left, Index: index}
+}
+
+// Parses the hash literal with comma separated key and value pairs.
+// A trailing comma before the '}' token is allowed.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []*ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		pair := &ast.HashPair{Key: p.parseExpression(LOWEST)}
+		if pair.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		pair.Value = p.parseExpression(LOWEST)
+		if pair.Value == nil {
+			return nil
+		}
+		hash.Pairs = append(hash.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return hash
+}
+
+// Parses the body of a function, which is outside of the loops around the function.
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	body := p.parseBlockStatement()
+	p.loopDepth = loopDepth
+
+	return body
+}
+
+// Parses the body of a loop, where break and continue are allowed.
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+
+	return body
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	statement := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	statement.Condition = p.parseExpression(LOWEST)
+	if statement.Condition == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseLoopBody()
+
+	return statement
+}
+
+// Parses the for-in statement.
+// Struct literals are not parsed in the iterable expression, as the '{' token starts the body.
+func (p *Parser) parseForInStatement() ast.Statement {
+	statement := &ast.ForInStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	p.noStructLiteral = true
+	statement.Iterable = p.parseExpression(LOWEST)
+	p.noStructLiteral = false
+	if statement.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseLoopBody()
+
+	return statement
+}
+
+// Parses the break or continue statement, which has to be inside of a loop.
+// The semicolon at the end is optional.
+func (p *Parser) parseBranchStatement() ast.Statement {
+	statement := &ast.BranchStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if p.loopDepth == 0 {
+		p.error(statement.Token, BranchOutsideLoop, "%s is not inside of a loop", statement.Token.Literal)
+		return nil
+	}
+
+	return statement
+}
+
+// Parses the assignment to the target, which must be an identifier, an index or a member expression.
+// The first token of the target is used to report the whole target, when it can't be assigned to.
+// The semicolon at the end is optional.
+func (p *Parser) parseAssignStatement(first token.Token, target ast.Expression) ast.Statement {
+	assignable := p.expectAssignable(first, p.curToken, target, "Cannot assign to %s")
+
+	p.nextToken()
+	statement := &ast.AssignStatement{Token: p.curToken, Target: target}
+
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	if !assignable {
+		return nil
+	}
+	return statement
+}
+
+// Parses the "++" or "--" after the target, which must be assignable.
+// The statement has to end after the operator, so "a--b" is reported instead of being parsed as "a--; b".
+// The semicolon at the end is optional.
+func (p *Parser) parseIncDecStatement(first token.Token, target ast.Expression) ast.Statement {
+	last := p.curToken
+	p.nextToken()
+	statement := &ast.IncDecStatement{Token: p.curToken, Target: target, Operator: p.curToken.Literal}
+
+	if !p.peekTokenIs(token.SEMICOLON) && !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		hint := ""
+		if p.curTokenIs(token.DECREMENT) {
+			hint = "Separate the minus signs to subtract a negative value, as in \"a - -b\"."
+		}
+		p.report(Diagnostic{
+			Start:    p.peekToken.Start,
+			End:      p.peekToken.End,
+			Severity: SeverityError,
+			Code:     UnexpectedToken,
+			Message:  fmt.Sprintf("Expected the end of the statement after %s. Got: %s", statement.Operator, p.peekToken.Type),
+			Hint:     hint,
+		})
+		return nil
+	}
+
+	format := "Cannot increment %s"
+	if p.curTokenIs(token.DECREMENT) {
+		format = "Cannot decrement %s"
+	}
+	if !p.expectAssignable(first, last, target, format) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+// Reports an error formatted with the target, if the target can't be assigned to.
+// The target spans from the first to the last token.
+func (p *Parser) expectAssignable(first, last token.Token, target ast.Expression, format string) bool {
+	if isAssignable(target) {
+		return true
+	}
+
+	// A missing target was already reported, when parsing it.
+	if target != nil {
+		p.report(Diagnostic{
+			Start:    first.Start,
+			End:      last.End,
+			Severity: SeverityError,
+			Code:     InvalidAssignment,
+			Message:  fmt.Sprintf(format, target),
+			Hint:     "Only identifiers, elements and fields can be assigned to.",
+		})
+	}
+	return false
+}
+
+// Reports whether a value can be assigned to the expression.
+func isAssignable(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.MemberExpression:
+		return true
+	default:
+		return false
+	}
+}
@@ -3,9 +3,11 @@ package parser
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"../ast"
 	"../lexer"
+	"../token"
 )
 
 func TestAsStatements(t *testing.T) {
@@ -31,10 +33,11 @@ func TestAsStatements(t *testing.T) {
 
 	tests := []struct {
 		expectedIdentifier string
+		expectedValue      interface{}
 	}{
-		{"x"},
-		{"y"},
-		{"z"},
+		{"x", 5},
+		{"y", 10},
+		{"z", 895678},
 	}
 
 	for i, tt := range tests {
@@ -42,6 +45,169 @@ func TestAsStatements(t *testing.T) {
 		if !testAsStatements(t, statement, tt.expectedIdentifier) {
 			return
 		}
+
+		value := statement.(*ast.DeclareStatement).Value
+		if !testLiteralExpression(t, value, tt.expectedValue) {
+			return
+		}
+	}
+}
+
+func TestAsStatementExpressions(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      string
+	}{
+		{"as x = 5 + 5 * 2;", "x", "(5 + (5 * 2))"},
+		{"as y = true", "y", "true"},
+		{"as z = -y == !x;", "z", "((-y) == (!x))"},
+		{"as s = \"text\"", "s", "\"text\""},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Program doesn't contain 1 statement, got: %d",
+				len(program.Statements))
+		}
+
+		statement := program.Statements[0]
+		if !testAsStatements(t, statement, tt.expectedIdentifier) {
+			return
+		}
+
+		value := statement.(*ast.DeclareStatement).Value
+		if value == nil || value.String() != tt.expectedValue {
+			t.Fatalf("Statement's value is not %q. Got: %v",
+				tt.expectedValue, value)
+		}
+	}
+}
+
+func TestAsStatementTypes(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType string
+		expected     string
+	}{
+		{`as name: string = "Adrian Plavka";`, "string", `as name: string = "Adrian Plavka";`},
+		{"as count: int;", "int", "as count: int;"},
+		{"as count: int", "int", "as count: int;"},
+		{"as p: Point = origin", "Point", "as p: Point = origin;"},
+		{"as xs: []float;", "[]float", "as xs: []float;"},
+		{"as f: (int) int = fn(x) { x }", "(int) int", "as f: (int) int = fn(x) x;"},
+		{"as x = 5;", "", "as x = 5;"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Program doesn't contain 1 statement, got: %d",
+				len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.DeclareStatement)
+		if !ok {
+			t.Fatalf("Statement not a DeclareStatement. Got: %T",
+				program.Statements[0])
+		}
+
+		if tt.expectedType == "" && statement.Type != nil {
+			t.Errorf("Expected no type for %q. Got: %q",
+				tt.input, statement.Type.String())
+		}
+		if tt.expectedType != "" && (statement.Type == nil || statement.Type.String() != tt.expectedType) {
+			t.Errorf("Expected type %q for %q. Got: %v",
+				tt.expectedType, tt.input, statement.Type)
+		}
+
+		if statement.String() != tt.expected {
+			t.Errorf("Expected statement %q. Got: %q",
+				tt.expected, statement.String())
+		}
+	}
+}
+
+func TestAsStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"as x;", "Expected next token to be =. Got: ;"},
+		{"as x: = 5;", "Expected a type. Got: ="},
+		{"as x: int 5;", "Expected next token to be =. Got: INT"},
+	}
+
+	for _, tt := range tests {
+		checkFirstError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	input := `
+	/// The answer.
+	/// Computed by Deep Thought.
+	as x = 42;
+
+	// Not a doc comment.
+	as y = 10;
+	/* Skipped. */ x;
+	`
+
+	lex := lexer.New(input)
+	lex.SetMode(lexer.ScanComments)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("Program doesn't contain 3 statements, got: %d",
+			len(program.Statements))
+	}
+
+	first := program.Statements[0].(*ast.DeclareStatement)
+	if first.Doc == nil {
+		t.Fatalf("Statement doesn't have a doc comment.")
+	}
+	if first.Doc.Text() != "The answer.\nComputed by Deep Thought." {
+		t.Errorf("Statement doesn't have the expected doc comment. Got: %q",
+			first.Doc.Text())
+	}
+
+	second := program.Statements[1].(*ast.DeclareStatement)
+	if second.Doc != nil {
+		t.Errorf("Statement shouldn't have a doc comment. Got: %q",
+			second.Doc.Text())
+	}
+}
+
+// The parser doesn't change the mode of the lexer, so comments are skipped unless the caller scans them.
+func TestDocCommentsNotScanned(t *testing.T) {
+	input := "/// The answer.\nas x = 42;"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if lex.Mode() != 0 {
+		t.Errorf("Expected the lexer mode to stay 0. Got: %d", lex.Mode())
+	}
+	if len(program.Statements) != 1 {
+		t.Fatalf("Program doesn't contain 1 statement, got: %d",
+			len(program.Statements))
+	}
+	if doc := program.Statements[0].(*ast.DeclareStatement).Doc; doc != nil {
+		t.Errorf("Statement shouldn't have a doc comment. Got: %q", doc.Text())
 	}
 }
 
@@ -87,6 +253,158 @@ func checkParseErrors(t *testing.T, par *Parser) {
 	t.FailNow()
 }
 
+// Parses the input and checks that its first error has the expected message.
+func checkFirstError(t *testing.T, input string, expected string) *ast.Program {
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+
+	errors := par.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("Expected an error for %q. Got none.", input)
+	}
+	if errors[0].Message != expected {
+		t.Errorf("Expected error %q for %q. Got: %q", expected, input, errors[0].Message)
+	}
+	return program
+}
+
+func TestLexerErrors(t *testing.T) {
+	input := `as = 5; x @ 5; "open`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	par.Parse()
+
+	expectedErrors := []string{
+		"unexpected character '@' at 1:11",
+		"unterminated string at 1:16",
+		"Expected next token to be IDENT. Got: = at 1:4",
+	}
+
+	errors := par.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors. Got: %q", len(expectedErrors), errors)
+	}
+	for i, expected := range expectedErrors {
+		if errors[i].String() != expected {
+			t.Errorf("Expected error %q. Got: %q", expected, errors[i])
+		}
+	}
+}
+
+// Statements with illegal tokens are left out of the program, as the lexer reports them.
+func TestIllegalTokenStatements(t *testing.T) {
+	tests := []string{
+		"1 + @;",
+		"f(@);",
+		"-@;",
+		"if (@) { }",
+		`as x = "a\qb";`,
+		`as s = "open`,
+	}
+
+	for _, input := range tests {
+		lex := lexer.New(input)
+		par := New(lex)
+		program := par.Parse()
+
+		if len(par.Errors()) == 0 {
+			t.Errorf("Expected an error for %q. Got none.", input)
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %d",
+				input, len(program.Statements))
+		}
+		if program.String() != "" {
+			t.Errorf("Expected an empty program for %q. Got: %q", input, program.String())
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	input := `x @ 1
+as = 5;
+1 + 2 = 3;
+break;
+as n: = 1;
+0xFFFFFFFFFFFFFFFFF;
+f(1 +
+);`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	par.Parse()
+
+	expected := []Diagnostic{
+		{
+			Start: token.Position{Line: 1, Column: 3, Offset: 2}, End: token.Position{Line: 1, Column: 4, Offset: 3},
+			Code: LexicalError, Message: "unexpected character '@'",
+		},
+		{
+			Start: token.Position{Line: 2, Column: 4, Offset: 9}, End: token.Position{Line: 2, Column: 5, Offset: 10},
+			Code: UnexpectedToken, Message: "Expected next token to be IDENT. Got: =",
+		},
+		{
+			Start: token.Position{Line: 3, Column: 1, Offset: 14}, End: token.Position{Line: 3, Column: 6, Offset: 19},
+			Code: InvalidAssignment, Message: "Cannot assign to (1 + 2)",
+			Hint: "Only identifiers, elements and fields can be assigned to.",
+		},
+		{
+			Start: token.Position{Line: 4, Column: 1, Offset: 25}, End: token.Position{Line: 4, Column: 6, Offset: 30},
+			Code: BranchOutsideLoop, Message: "break is not inside of a loop",
+		},
+		{
+			Start: token.Position{Line: 5, Column: 7, Offset: 38}, End: token.Position{Line: 5, Column: 8, Offset: 39},
+			Code: MissingType, Message: "Expected a type. Got: =",
+		},
+		{
+			Start: token.Position{Line: 6, Column: 1, Offset: 43}, End: token.Position{Line: 6, Column: 20, Offset: 62},
+			Code: InvalidNumber, Message: `Could not parse "0xFFFFFFFFFFFFFFFFF" as integer`,
+		},
+		{
+			Start: token.Position{Line: 8, Column: 1, Offset: 70}, End: token.Position{Line: 8, Column: 2, Offset: 71},
+			Code: MissingExpression, Message: "No prefix parse function for ) found",
+		},
+	}
+
+	errors := par.Errors()
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected %d errors. Got: %q", len(expected), errors)
+	}
+	for i, diagnostic := range errors {
+		if diagnostic.Severity != SeverityError {
+			t.Errorf("Expected severity %s for %q. Got: %s",
+				SeverityError, diagnostic.Message, diagnostic.Severity)
+		}
+		expected[i].Severity = SeverityError
+		if diagnostic != expected[i] {
+			t.Errorf("Expected diagnostic %#v. Got: %#v", expected[i], diagnostic)
+		}
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	diagnostic := Diagnostic{
+		Start:    token.Position{Line: 3, Column: 7, Offset: 20},
+		End:      token.Position{Line: 3, Column: 9, Offset: 22},
+		Severity: SeverityWarning,
+		Code:     UnexpectedToken,
+		Message:  "Expected next token to be ). Got: EOF",
+	}
+
+	expected := "Expected next token to be ). Got: EOF at 3:7"
+	if diagnostic.String() != expected {
+		t.Errorf("Expected %q. Got: %q", expected, diagnostic.String())
+	}
+	if fmt.Sprint(diagnostic) != expected {
+		t.Errorf("Expected the diagnostic to print as %q. Got: %q", expected, fmt.Sprint(diagnostic))
+	}
+	if diagnostic.Severity.String() != "warning" {
+		t.Errorf("Expected severity %q. Got: %q", "warning", diagnostic.Severity.String())
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	input := `
 	ret 5;
@@ -104,7 +422,8 @@ func TestReturnStatements(t *testing.T) {
 			len(program.Statements))
 	}
 
-	for _, statement := range program.Statements {
+	expectedValues := []int64{5, 10, 959854}
+	for i, statement := range program.Statements {
 		returnStatement, ok := statement.(*ast.ReturnStatement)
 		if !ok {
 			t.Errorf("Statement not a ReturnStatement. Got: %T", statement)
@@ -114,6 +433,69 @@ func TestReturnStatements(t *testing.T) {
 			t.Errorf("ReturnStatement's token literal not 'rt'. Got: %q",
 				returnStatement.TokenLiteral())
 		}
+		testIntegerLiteral(t, returnStatement.Value, expectedValues[i])
+	}
+}
+
+func TestReturnStatementExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ret x + y;", "ret (x + y);"},
+		{"ret;", "ret;"},
+		{"ret", "ret;"},
+		{"ret a * b", "ret (a * b);"},
+		{"if (x) { ret }", "ifx ret;"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if program.String() != tt.expected {
+			t.Fatalf("Expected program %q. Got: %q",
+				tt.expected, program.String())
+		}
+	}
+}
+
+func TestInsertedSemicolons(t *testing.T) {
+	withSemicolons := `
+	a + b * c;
+	if (x < y) { x; } else { y; };
+	-a;;
+	ret 5;
+	`
+	withoutSemicolons := `
+	a + b *
+		c
+	if (x < y) { x } else { y }
+	-a
+	ret 5
+	`
+
+	lex := lexer.New(withSemicolons)
+	par := New(lex)
+	expected := par.Parse()
+	checkParseErrors(t, par)
+
+	lex = lexer.New(withoutSemicolons)
+	lex.SetMode(lexer.InsertSemis)
+	par = New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != len(expected.Statements) {
+		t.Fatalf("Expected %d statements. Got: %d",
+			len(expected.Statements), len(program.Statements))
+	}
+
+	if program.String() != expected.String() {
+		t.Fatalf("Programs don't match. Expected: %q. Got: %q",
+			expected.String(), program.String())
 	}
 }
 
@@ -179,7 +561,7 @@ func TestIntegerIdentifierExpression(t *testing.T) {
 	}
 
 	if ident.Value != 10 {
-		t.Fatalf("Identifier does not contain a value '10'. Got: %q",
+		t.Fatalf("Identifier does not contain a value '10'. Got: %d",
 			ident.Value)
 	}
 
@@ -189,6 +571,104 @@ func TestIntegerIdentifierExpression(t *testing.T) {
 	}
 }
 
+func TestNumberLiteralExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"007;", 7},
+		{"1_000_000;", 1000000},
+		{"0xFF;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+		{"3.14;", 3.14},
+		{"1e-9;", 1e-9},
+		{"1_000.5;", 1000.5},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Program should contain only one statement. Got: %d",
+				len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Statement is not a ExpressionStatement. Got: %T",
+				program.Statements[0])
+		}
+
+		switch expected := tt.expected.(type) {
+		case int:
+			literal, ok := statement.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Fatalf("Expression not an IntegerLiteral. Got: %T",
+					statement.Expression)
+			}
+			if literal.Value != int64(expected) {
+				t.Fatalf("Values don't match for %q. Expected: %d. Got: %d",
+					tt.input, expected, literal.Value)
+			}
+		case float64:
+			literal, ok := statement.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Fatalf("Expression not a FloatLiteral. Got: %T",
+					statement.Expression)
+			}
+			if literal.Value != expected {
+				t.Fatalf("Values don't match for %q. Expected: %g. Got: %g",
+					tt.input, expected, literal.Value)
+			}
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello\tworld";`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Program should contain only one statement. Got: %d",
+			len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statement is not a ExpressionStatement. Got: %T",
+			program.Statements[0])
+	}
+
+	literal, ok := statement.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("Statement does not contain a StringLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	if literal.Value != "hello\tworld" {
+		t.Fatalf("StringLiteral does not contain a value %q. Got: %q",
+			"hello\tworld", literal.Value)
+	}
+
+	if literal.TokenLiteral() != `"hello\tworld"` {
+		t.Fatalf("StringLiteral does not keep the source text %q. Got: %q",
+			`"hello\tworld"`, literal.TokenLiteral())
+	}
+
+	if program.String() != `"hello\tworld"` {
+		t.Fatalf("StringLiteral's String does not round-trip. Got: %q",
+			program.String())
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -365,27 +845,20 @@ func testInfixExpression(
 	return true
 }
 
-func TestParsingInfixExpressions(t *testing.T) {
-	infixTests := []struct {
+func TestParsingLogicalExpressions(t *testing.T) {
+	tests := []struct {
 		input      string
 		leftValue  interface{}
 		operator   string
 		rightValue interface{}
 	}{
-		{"5 + 5", 5, "+", 5},
-		{"5 - 5", 5, "-", 5},
-		{"5 * 5", 5, "*", 5},
-		{"5 / 5", 5, "/", 5},
-		{"5 < 5", 5, "<", 5},
-		{"5 > 5", 5, ">", 5},
-		{"5 == 5", 5, "==", 5},
-		{"5 != 5", 5, "!=", 5},
-		{"true == true", true, "==", true},
-		{"false == false", false, "==", false},
-		{"true != false", true, "!=", false},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
+		{"a && b", "a", "&&", "b"},
+		{"a || 1", "a", "||", 1},
 	}
 
-	for _, tt := range infixTests {
+	for _, tt := range tests {
 		lex := lexer.New(tt.input)
 		par := New(lex)
 		program := par.Parse()
@@ -402,9 +875,9 @@ func TestParsingInfixExpressions(t *testing.T) {
 				program.Statements[0])
 		}
 
-		expression, ok := statement.Expression.(*ast.InfixExpression)
+		expression, ok := statement.Expression.(*ast.LogicalExpression)
 		if !ok {
-			t.Fatalf("Expected an InfixExpression. Got: %T",
+			t.Fatalf("Expected a LogicalExpression. Got: %T",
 				statement.Expression)
 		}
 
@@ -413,7 +886,7 @@ func TestParsingInfixExpressions(t *testing.T) {
 		}
 
 		if expression.Operator != tt.operator {
-			t.Fatalf("Expected a %q operator. Got: %q",
+			t.Fatalf("Expected operator %q. Got: %q",
 				tt.operator, expression.Operator)
 		}
 
@@ -423,13 +896,100 @@ func TestParsingInfixExpressions(t *testing.T) {
 	}
 }
 
-func TestOperatorPrecedenceParsing(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"-a * b", "((-a) * b)"},
-		{"!-a", "(!(-a))"},
+func TestLogicalExpressionOperands(t *testing.T) {
+	lex := lexer.New("a == 1 || b && !c")
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	or, ok := statement.Expression.(*ast.LogicalExpression)
+	if !ok || or.Operator != "||" {
+		t.Fatalf("Expected a || LogicalExpression. Got: %s", statement.Expression)
+	}
+
+	if !testInfixExpression(t, or.Left, "a", "==", 1) {
+		return
+	}
+
+	and, ok := or.Right.(*ast.LogicalExpression)
+	if !ok || and.Operator != "&&" {
+		t.Fatalf("Expected a && LogicalExpression. Got: %s", or.Right)
+	}
+
+	if _, ok := and.Right.(*ast.PrefixExpression); !ok {
+		t.Errorf("Expected a PrefixExpression. Got: %T", and.Right)
+	}
+}
+
+func TestParsingInfixExpressions(t *testing.T) {
+	infixTests := []struct {
+		input      string
+		leftValue  interface{}
+		operator   string
+		rightValue interface{}
+	}{
+		{"5 + 5", 5, "+", 5},
+		{"5 - 5", 5, "-", 5},
+		{"5 * 5", 5, "*", 5},
+		{"5 / 5", 5, "/", 5},
+		{"5 < 5", 5, "<", 5},
+		{"5 > 5", 5, ">", 5},
+		{"5 == 5", 5, "==", 5},
+		{"5 != 5", 5, "!=", 5},
+		{"5 % 5", 5, "%", 5},
+		{"5 <= 5", 5, "<=", 5},
+		{"5 >= 5", 5, ">=", 5},
+		{"true == true", true, "==", true},
+		{"false == false", false, "==", false},
+		{"true != false", true, "!=", false},
+	}
+
+	for _, tt := range infixTests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected only %d expression statement. Got: %d",
+				1, len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("Expected an ExpressionStatement. Got: %T",
+				program.Statements[0])
+		}
+
+		expression, ok := statement.Expression.(*ast.InfixExpression)
+		if !ok {
+			t.Fatalf("Expected an InfixExpression. Got: %T",
+				statement.Expression)
+		}
+
+		if !testLiteralExpression(t, expression.Left, tt.leftValue) {
+			return
+		}
+
+		if expression.Operator != tt.operator {
+			t.Fatalf("Expected a %q operator. Got: %q",
+				tt.operator, expression.Operator)
+		}
+
+		if !testLiteralExpression(t, expression.Right, tt.rightValue) {
+			return
+		}
+	}
+}
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a * b", "((-a) * b)"},
+		{"!-a", "(!(-a))"},
 		{"a + b + c", "((a + b) + c)"},
 		{"a * b * c", "((a * b) * c)"},
 		{"a * b / c", "((a * b) / c)"},
@@ -442,6 +1002,48 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		{"-(5 + 5)", "(-(5 + 5))"},
 		{"!(true == true)", "(!(true == true))"},
 		{"2 / (5 * 5)", "(2 / (5 * 5))"},
+		{"a + b % c", "(a + (b % c))"},
+		{"a % b * c", "((a % b) * c)"},
+		{"a <= b == c >= d", "((a <= b) == (c >= d))"},
+		{"a == b && c != d", "((a == b) && (c != d))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"!a || b < c", "((!a) || (b < c))"},
+		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"add(1, mul(2, 3))(4)", "add(1, mul(2, 3))(4)"},
+		{"-f(x)", "(-f(x))"},
+		{"a.b.c", "a.b.c"},
+		{"-p.x * 2", "((-p.x) * 2)"},
+		{"p.scale(2).x + 1", "(p.scale(2).x + 1)"},
+		{"(a + b).x", "(a + b).x"},
+		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"fns[0](1)", "(fns[0])(1)"},
+		{"-xs[1:2]", "(-(xs[1:2]))"},
+		{"p.xs[i + 1]", "(p.xs[(i + 1)])"},
+		{"matrix[i][j]", "((matrix[i])[j])"},
+		{"a ** b", "(a ** b)"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a ** b * c", "((a ** b) * c)"},
+		// A prefix operator applies to the whole power, so "-2 ** 2" is -4.
+		{"-a ** b", "(-(a ** b))"},
+		{"!a ** b", "(!(a ** b))"},
+		{"-a ** b * c", "((-(a ** b)) * c)"},
+		{"a ** -b", "(a ** (-b))"},
+		{"a ** -b ** c", "(a ** (-(b ** c)))"},
+		{"-a ** -b", "(-(a ** (-b)))"},
+		{"a ** b.c ** d[0]", "(a ** (b.c ** (d[0])))"},
+		{"a - b - c ** d ** e", "((a - b) - (c ** (d ** e)))"},
+		{"xs |> f()", "(xs |> f())"},
+		{"xs |> map(f) |> filter(g) |> format()", "(((xs |> map(f)) |> filter(g)) |> format())"},
+		{"a + b |> f(c * d)", "((a + b) |> f((c * d)))"},
+		{"a || b |> f()", "((a || b) |> f())"},
+		{"xs |> fns[0](1)", "(xs |> (fns[0])(1))"},
+		{"xs |> p.scale(2)", "(xs |> p.scale(2))"},
+		{"f(xs |> g())", "f((xs |> g()))"},
 	}
 
 	for _, tt := range tests {
@@ -570,3 +1172,1328 @@ func TestIfElseExpression(t *testing.T) {
 		return
 	}
 }
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected one program statement. Got: %d",
+			len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Expected an ExpressionStatement. Got: %T",
+			program.Statements[0])
+	}
+
+	function, ok := statement.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("Expected a FunctionLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("Expected 2 parameters. Got: %d",
+			len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("Expected one body statement. Got: %d",
+			len(function.Body.Statements))
+	}
+
+	bodyStatement, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Body statement not an ExpressionStatement. Got: %T",
+			function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStatement.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{"fn() {};", []string{}},
+		{"fn(x) {};", []string{"x"}},
+		{"fn(x, y, z) {};", []string{"x", "y", "z"}},
+		{"fn(x, y,) {};", []string{"x", "y"}},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		function := statement.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("Expected %d parameters. Got: %d",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+
+		for i, identifier := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], identifier)
+		}
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5,);"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected one program statement. Got: %d",
+			len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Expected an ExpressionStatement. Got: %T",
+			program.Statements[0])
+	}
+
+	expression, ok := statement.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("Expected a CallExpression. Got: %T",
+			statement.Expression)
+	}
+
+	if !testIdentifier(t, expression.Function, "add") {
+		return
+	}
+
+	if len(expression.Arguments) != 3 {
+		t.Fatalf("Expected 3 arguments. Got: %d",
+			len(expression.Arguments))
+	}
+
+	testLiteralExpression(t, expression.Arguments[0], 1)
+	testInfixExpression(t, expression.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, expression.Arguments[2], 4, "+", 5)
+}
+
+func TestNestedCallExpressionParsing(t *testing.T) {
+	input := "add(1, mul(2, 3))(4);"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	outer, ok := statement.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("Expected a CallExpression. Got: %T",
+			statement.Expression)
+	}
+
+	if len(outer.Arguments) != 1 || !testLiteralExpression(t, outer.Arguments[0], 4) {
+		t.Fatalf("Expected the outer call to have the argument 4. Got: %v",
+			outer.Arguments)
+	}
+
+	inner, ok := outer.Function.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("Expected the callee to be a CallExpression. Got: %T",
+			outer.Function)
+	}
+
+	if !testIdentifier(t, inner.Function, "add") {
+		return
+	}
+
+	if _, ok := inner.Arguments[1].(*ast.CallExpression); !ok {
+		t.Fatalf("Expected the second argument to be a CallExpression. Got: %T",
+			inner.Arguments[1])
+	}
+}
+
+func TestFunctionDeclarationParsing(t *testing.T) {
+	input := `
+	/// Adds two numbers.
+	fn add(x: int, y: int) of Point {
+		ret x + y;
+	}
+	`
+
+	lex := lexer.New(input)
+	lex.SetMode(lexer.ScanComments)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected one program statement. Got: %d",
+			len(program.Statements))
+	}
+
+	declaration, ok := program.Statements[0].(*ast.FunctionDeclaration)
+	if !ok {
+		t.Fatalf("Expected a FunctionDeclaration. Got: %T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, declaration.Name, "add") {
+		return
+	}
+
+	if len(declaration.Parameters) != 2 {
+		t.Fatalf("Expected 2 parameters. Got: %d",
+			len(declaration.Parameters))
+	}
+
+	for i, expected := range []string{"x: int", "y: int"} {
+		if declaration.Parameters[i].String() != expected {
+			t.Errorf("Expected parameter %q. Got: %q",
+				expected, declaration.Parameters[i].String())
+		}
+	}
+
+	if _, ok := declaration.Parameters[0].Type.(*ast.SimpleType); !ok {
+		t.Errorf("Expected a SimpleType parameter. Got: %T",
+			declaration.Parameters[0].Type)
+	}
+
+	returnType, ok := declaration.ReturnType.(*ast.NamedType)
+	if !ok || returnType.Name != "Point" {
+		t.Fatalf("Expected the NamedType Point as the return type. Got: %v",
+			declaration.ReturnType)
+	}
+
+	if declaration.Doc == nil || declaration.Doc.Text() != "Adds two numbers." {
+		t.Errorf("Expected the declaration to have a doc comment.")
+	}
+
+	if declaration.String() != "fn add(x: int, y: int) of Point ret (x + y);" {
+		t.Errorf("Unexpected declaration string. Got: %q",
+			declaration.String())
+	}
+}
+
+func TestTypeParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn f() {}", "fn f() "},
+		{"fn f(a: int,) of bool {}", "fn f(a: int) of bool "},
+		{"fn f(p: Point) of []Point {}", "fn f(p: Point) of []Point "},
+		{"fn f(xs: [][]string) {}", "fn f(xs: [][]string) "},
+		{"fn f(cb: (int, float) bool) {}", "fn f(cb: (int, float) bool) "},
+		{"fn f(cb: (), n: int) of (int) []int {}", "fn f(cb: (), n: int) of (int) []int "},
+		{"fn f(cb: (() int,) (int)) {}", "fn f(cb: (() int) (int)) "},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if program.String() != tt.expected {
+			t.Errorf("Expected program %q. Got: %q",
+				tt.expected, program.String())
+		}
+	}
+}
+
+func TestFunctionDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"fn f(x) {}", "Expected next token to be :. Got: )"},
+		{"fn f(x: 5) {}", "Expected a type. Got: INT"},
+		{"fn f() of {}", "Expected a type. Got: {"},
+		{"fn f(x: int y: int) {}", "Expected next token to be ,. Got: IDENT"},
+	}
+
+	for _, tt := range tests {
+		checkFirstError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestStructDeclarationParsing(t *testing.T) {
+	input := `
+	struct Point {
+		x: int,
+		y: int,
+	}
+	struct Empty {}
+	struct Line { from: Point; to: Point }
+	`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("Expected 3 program statements. Got: %d",
+			len(program.Statements))
+	}
+
+	declaration, ok := program.Statements[0].(*ast.StructDeclaration)
+	if !ok {
+		t.Fatalf("Expected a StructDeclaration. Got: %T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, declaration.Name, "Point") {
+		return
+	}
+
+	if len(declaration.Fields) != 2 {
+		t.Fatalf("Expected 2 fields. Got: %d",
+			len(declaration.Fields))
+	}
+
+	for i, expected := range []string{"x: int", "y: int"} {
+		if declaration.Fields[i].String() != expected {
+			t.Errorf("Expected field %q. Got: %q",
+				expected, declaration.Fields[i].String())
+		}
+	}
+
+	expected := []string{
+		"struct Point { x: int, y: int }",
+		"struct Empty {}",
+		"struct Line { from: Point, to: Point }",
+	}
+	for i, statement := range program.Statements {
+		if statement.String() != expected[i] {
+			t.Errorf("Expected statement %q. Got: %q",
+				expected[i], statement.String())
+		}
+	}
+}
+
+func TestStructLiteralParsing(t *testing.T) {
+	input := "Point { x: 1, y: 2 + 3, };"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := statement.Expression.(*ast.StructLiteral)
+	if !ok {
+		t.Fatalf("Expected a StructLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	if !testIdentifier(t, literal.Name, "Point") {
+		return
+	}
+
+	if len(literal.Fields) != 2 {
+		t.Fatalf("Expected 2 fields. Got: %d",
+			len(literal.Fields))
+	}
+
+	if !testIdentifier(t, literal.Fields[0].Name, "x") || !testLiteralExpression(t, literal.Fields[0].Value, 1) {
+		return
+	}
+
+	if !testIdentifier(t, literal.Fields[1].Name, "y") || !testInfixExpression(t, literal.Fields[1].Value, 2, "+", 3) {
+		return
+	}
+}
+
+func TestMemberExpressionParsing(t *testing.T) {
+	input := "p.x;"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	member, ok := statement.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("Expected a MemberExpression. Got: %T",
+			statement.Expression)
+	}
+
+	if !testIdentifier(t, member.Object, "p") || !testIdentifier(t, member.Property, "x") {
+		return
+	}
+}
+
+func TestStructRoundTrip(t *testing.T) {
+	tests := []string{
+		"struct Point { x: int, y: int }",
+		"struct Node { value: float, children: []Node, visit: (Node) bool }",
+		"as p = Point { x: 1, y: (2 * 3) };",
+		"as q = Point {};",
+		"Line { from: Point { x: 0, y: 0 }, to: p }.to.x",
+		"(p.x + make(p).y)",
+	}
+
+	for _, input := range tests {
+		lex := lexer.New(input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if program.String() != input {
+			t.Errorf("Program doesn't round-trip. Expected: %q. Got: %q",
+				input, program.String())
+		}
+	}
+}
+
+func TestInterfaceDeclarationParsing(t *testing.T) {
+	input := `
+	/// Things that can be added.
+	interface Addable {
+		add: (int, int) int,
+		reset: (),
+		map: ((int) int) []int
+	}
+	interface Empty {}
+	`
+
+	lex := lexer.New(input)
+	lex.SetMode(lexer.ScanComments)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Expected 2 program statements. Got: %d",
+			len(program.Statements))
+	}
+
+	declaration, ok := program.Statements[0].(*ast.InterfaceDeclaration)
+	if !ok {
+		t.Fatalf("Expected an InterfaceDeclaration. Got: %T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, declaration.Name, "Addable") {
+		return
+	}
+
+	if declaration.Doc == nil || declaration.Doc.Text() != "Things that can be added." {
+		t.Errorf("Expected the declaration to have a doc comment.")
+	}
+
+	tests := []struct {
+		name           string
+		parameterCount int
+		returnType     string
+	}{
+		{"add", 2, "int"},
+		{"reset", 0, ""},
+		{"map", 1, "[]int"},
+	}
+
+	if len(declaration.Methods) != len(tests) {
+		t.Fatalf("Expected %d methods. Got: %d",
+			len(tests), len(declaration.Methods))
+	}
+
+	for i, tt := range tests {
+		method := declaration.Methods[i]
+		if !testIdentifier(t, method.Name, tt.name) {
+			return
+		}
+		if len(method.Type.Parameters) != tt.parameterCount {
+			t.Errorf("Expected %d parameters for %s. Got: %d",
+				tt.parameterCount, tt.name, len(method.Type.Parameters))
+		}
+		if tt.returnType == "" && method.Type.Return != nil {
+			t.Errorf("Expected no return type for %s. Got: %q",
+				tt.name, method.Type.Return.String())
+		}
+		if tt.returnType != "" && (method.Type.Return == nil || method.Type.Return.String() != tt.returnType) {
+			t.Errorf("Expected return type %q for %s. Got: %v",
+				tt.returnType, tt.name, method.Type.Return)
+		}
+	}
+
+	expected := "interface Addable { add: (int, int) int, reset: (), map: ((int) int) []int }"
+	if declaration.String() != expected {
+		t.Errorf("Expected declaration %q. Got: %q",
+			expected, declaration.String())
+	}
+
+	if program.Statements[1].String() != "interface Empty {}" {
+		t.Errorf("Expected an empty interface. Got: %q",
+			program.Statements[1].String())
+	}
+}
+
+func TestInterfaceDeclarationErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"interface { }", "Expected next token to be IDENT. Got: {"},
+		{"interface A { add: int }", "Expected next token to be (. Got: IDENT"},
+		{"interface A { add (int) }", "Expected next token to be :. Got: ("},
+		{"interface A { a: () int b: () }", "Expected next token to be ,. Got: IDENT"},
+	}
+
+	for _, tt := range tests {
+		checkFirstError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestPreludeParsing(t *testing.T) {
+	input := `
+as name: string = "Adrian Plavka";
+
+struct Point {
+    x: int,
+    y: int, 
+}
+
+interface Addable {
+    add: (int, int) int
+}
+
+fn add(x: int, y: int) of Point {
+    ret x + y;
+}
+`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	expected := []string{
+		`as name: string = "Adrian Plavka";`,
+		"struct Point { x: int, y: int }",
+		"interface Addable { add: (int, int) int }",
+		"fn add(x: int, y: int) of Point ret (x + y);",
+	}
+
+	if len(program.Statements) != len(expected) {
+		t.Fatalf("Expected %d program statements. Got: %d",
+			len(expected), len(program.Statements))
+	}
+
+	for i, statement := range program.Statements {
+		if statement.String() != expected[i] {
+			t.Errorf("Expected statement %q. Got: %q",
+				expected[i], statement.String())
+		}
+	}
+}
+
+func TestArrayLiteralParsing(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3,]"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := statement.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("Expected an ArrayLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf("Expected 3 elements. Got: %d",
+			len(array.Elements))
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestEmptyArrayLiteralParsing(t *testing.T) {
+	input := "[]"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	array, ok := statement.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf("Expected an ArrayLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Fatalf("Expected no elements. Got: %d",
+			len(array.Elements))
+	}
+}
+
+func TestIndexExpressionParsing(t *testing.T) {
+	input := "xs[1 + 1]"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	index, ok := statement.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("Expected an IndexExpression. Got: %T",
+			statement.Expression)
+	}
+
+	if !testIdentifier(t, index.Left, "xs") {
+		return
+	}
+
+	testInfixExpression(t, index.Index, 1, "+", 1)
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		low   interface{}
+		high  interface{}
+	}{
+		{"xs[1:3]", 1, 3},
+		{"xs[:2]", nil, 2},
+		{"xs[2:]", 2, nil},
+		{"xs[:]", nil, nil},
+		{"xs[i:n]", "i", "n"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := statement.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("Expected a SliceExpression for %q. Got: %T",
+				tt.input, statement.Expression)
+		}
+
+		if !testIdentifier(t, slice.Left, "xs") {
+			return
+		}
+
+		if tt.low == nil && slice.Low != nil {
+			t.Errorf("Expected no low bound for %q. Got: %q", tt.input, slice.Low.String())
+		} else if tt.low != nil {
+			testLiteralExpression(t, slice.Low, tt.low)
+		}
+
+		if tt.high == nil && slice.High != nil {
+			t.Errorf("Expected no high bound for %q. Got: %q", tt.input, slice.High.String())
+		} else if tt.high != nil {
+			testLiteralExpression(t, slice.High, tt.high)
+		}
+
+		if program.String() != "("+tt.input+")" {
+			t.Errorf("Expected program %q. Got: %q", "("+tt.input+")", program.String())
+		}
+	}
+}
+
+func TestHashLiteralParsing(t *testing.T) {
+	input := `({"one": 1, two: 2, 1 + 2: "three", [4]: 4 * 1,})`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := statement.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("Expected a HashLiteral. Got: %T",
+			statement.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value string
+	}{
+		{`"one"`, "1"},
+		{"two", "2"},
+		{"(1 + 2)", `"three"`},
+		{"[4]", "(4 * 1)"},
+	}
+
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("Expected %d pairs. Got: %d",
+			len(expected), len(hash.Pairs))
+	}
+
+	for i, pair := range hash.Pairs {
+		if pair.Key.String() != expected[i].key {
+			t.Errorf("Expected key %q. Got: %q", expected[i].key, pair.Key.String())
+		}
+		if pair.Value.String() != expected[i].value {
+			t.Errorf("Expected value %q. Got: %q", expected[i].value, pair.Value.String())
+		}
+	}
+
+	if _, ok := hash.Pairs[0].Key.(*ast.StringLiteral); !ok {
+		t.Errorf("Expected a StringLiteral key. Got: %T", hash.Pairs[0].Key)
+	}
+	if _, ok := hash.Pairs[1].Key.(*ast.Identifier); !ok {
+		t.Errorf("Expected an Identifier key. Got: %T", hash.Pairs[1].Key)
+	}
+}
+
+func TestEmptyHashLiteralParsing(t *testing.T) {
+	input := "as empty = {};"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.DeclareStatement)
+	hash, ok := statement.Value.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("Expected a HashLiteral. Got: %T", statement.Value)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Fatalf("Expected no pairs. Got: %d", len(hash.Pairs))
+	}
+}
+
+// The '{' token starts a block in a statement position and a hash literal in an expression position.
+func TestBlockAndHashLiteralDisambiguation(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType string
+	}{
+		{"{ x; y }", "*ast.BlockStatement"},
+		{"{}", "*ast.BlockStatement"},
+		{"{ as x = {}; }", "*ast.BlockStatement"},
+		{"({ x: 1 })", "*ast.HashLiteral"},
+		{"as m = { x: 1 }", "*ast.HashLiteral"},
+		{"ret { x: 1 }", "*ast.HashLiteral"},
+		{"f({ x: 1 })", "*ast.CallExpression"},
+		{"Point { x: 1 }", "*ast.StructLiteral"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected one program statement for %q. Got: %d",
+				tt.input, len(program.Statements))
+		}
+
+		var node ast.Node = program.Statements[0]
+		switch statement := node.(type) {
+		case *ast.ExpressionStatement:
+			node = statement.Expression
+		case *ast.DeclareStatement:
+			node = statement.Value
+		case *ast.ReturnStatement:
+			node = statement.Value
+		}
+
+		if fmt.Sprintf("%T", node) != tt.expectedType {
+			t.Errorf("Expected %s for %q. Got: %T",
+				tt.expectedType, tt.input, node)
+		}
+	}
+
+	// A hash literal can't be a statement on it's own, unless it's wrapped in parentheses.
+	lex := lexer.New(`{ "a": 1 }`)
+	par := New(lex)
+	par.Parse()
+
+	if len(par.Errors()) == 0 {
+		t.Errorf("Expected an error for a hash literal in a statement position.")
+	}
+}
+
+func TestWhileStatementParsing(t *testing.T) {
+	input := "while (x < 10) { x; break; continue }"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Expected one program statement. Got: %d",
+			len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("Expected a WhileStatement. Got: %T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, statement.Condition, "x", "<", 10) {
+		return
+	}
+
+	if len(statement.Body.Statements) != 3 {
+		t.Fatalf("Expected 3 body statements. Got: %d",
+			len(statement.Body.Statements))
+	}
+
+	for i, keyword := range []string{"break", "continue"} {
+		branch, ok := statement.Body.Statements[i+1].(*ast.BranchStatement)
+		if !ok {
+			t.Fatalf("Expected a BranchStatement. Got: %T",
+				statement.Body.Statements[i+1])
+		}
+		if branch.TokenLiteral() != keyword {
+			t.Errorf("Expected a %s statement. Got: %q",
+				keyword, branch.TokenLiteral())
+		}
+	}
+}
+
+func TestForInStatementParsing(t *testing.T) {
+	input := "for x in xs { total += x }"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+
+	if len(program.Statements) == 0 {
+		t.Fatalf("Expected a program statement.")
+	}
+
+	statement, ok := program.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatalf("Expected a ForInStatement. Got: %T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, statement.Variable, "x") {
+		return
+	}
+
+	if !testIdentifier(t, statement.Iterable, "xs") {
+		return
+	}
+
+	if statement.Body == nil {
+		t.Fatalf("Expected the ForInStatement to have a body.")
+	}
+}
+
+func TestLoopParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (true) { break }", "while (true) { break; }"},
+		{"for p in points { p.x }", "for p in points { p.x }"},
+		{"for p in [Point { x: 1 }] { continue; }", "for p in [Point { x: 1 }] { continue; }"},
+		{"for p in (Point { x: 1 }).xs { p }", "for p in Point { x: 1 }.xs { p }"},
+		{"for i in range(Point { x: 1 }) { i }", "for i in range(Point { x: 1 }) { i }"},
+		{"for x in xs { for y in ys { break } continue }", "for x in xs { for y in ys { break; }continue; }"},
+		{"while (a) { if (b) { break } }", "while (a) { ifb break; }"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if program.String() != tt.expected {
+			t.Errorf("Expected program %q. Got: %q",
+				tt.expected, program.String())
+		}
+	}
+}
+
+// The loops are printed in a form, which parses back to the same program.
+func TestLoopRoundTrip(t *testing.T) {
+	tests := []string{
+		"while (x) { x++; }",
+		"while (done) {}",
+		"while (f(x)) { x = g(x); }",
+		"for p in points { p.x += 1; }",
+		"for x in xs { while (x) { break; } }",
+	}
+
+	for _, input := range tests {
+		lex := lexer.New(input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if program.String() != input {
+			t.Errorf("Expected program %q. Got: %q", input, program.String())
+		}
+
+		lex = lexer.New(program.String())
+		par = New(lex)
+		reparsed := par.Parse()
+		checkParseErrors(t, par)
+
+		if reparsed.String() != program.String() {
+			t.Errorf("Expected the program to parse back to %q. Got: %q",
+				program.String(), reparsed.String())
+		}
+	}
+}
+
+func TestBranchStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"break;", "break is not inside of a loop"},
+		{"continue", "continue is not inside of a loop"},
+		{"if (x) { break }", "break is not inside of a loop"},
+		{"while (x) { fn() { continue } }", "continue is not inside of a loop"},
+		{"for x in xs { fn f() { break } }", "break is not inside of a loop"},
+		{"for x in xs {}; break", "break is not inside of a loop"},
+		{"for in xs {}", "Expected next token to be IDENT. Got: IN"},
+		{"for x xs {}", "Expected next token to be IN. Got: IDENT"},
+		{"while x {}", "Expected next token to be (. Got: IDENT"},
+	}
+
+	for _, tt := range tests {
+		checkFirstError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestAssignStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTarget string
+		expectedOp     string
+		expectedValue  string
+	}{
+		{"x = x + 1;", "x", "=", "(x + 1)"},
+		{"x += 2", "x", "+=", "2"},
+		{"x -= y;", "x", "-=", "y"},
+		{"x *= 3;", "x", "*=", "3"},
+		{"x /= 4;", "x", "/=", "4"},
+		{"xs[0] = 1;", "(xs[0])", "=", "1"},
+		{"p.x += 2;", "p.x", "+=", "2"},
+		{"grid[i][j] = p.x * 2;", "((grid[i])[j])", "=", "(p.x * 2)"},
+		{"f().x = [1, 2];", "f().x", "=", "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected one program statement. Got: %d",
+				len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.AssignStatement)
+		if !ok {
+			t.Fatalf("Expected an AssignStatement. Got: %T",
+				program.Statements[0])
+		}
+
+		if statement.Target.String() != tt.expectedTarget {
+			t.Errorf("Expected target %q. Got: %q",
+				tt.expectedTarget, statement.Target.String())
+		}
+		if statement.TokenLiteral() != tt.expectedOp {
+			t.Errorf("Expected operator %q. Got: %q",
+				tt.expectedOp, statement.TokenLiteral())
+		}
+		if statement.Value.String() != tt.expectedValue {
+			t.Errorf("Expected value %q. Got: %q",
+				tt.expectedValue, statement.Value.String())
+		}
+	}
+}
+
+func TestAssignStatementsInBlocks(t *testing.T) {
+	input := `
+	fn inc(xs: []int) {
+		for i in range(len(xs)) {
+			xs[i] += 1
+		}
+	}
+	as total = 0
+	while (total < 10) { total = total + 1 }
+	`
+
+	lex := lexer.New(input)
+	lex.SetMode(lexer.InsertSemis)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	expected := "fn inc(xs: []int) for i in range(len(xs)) { (xs[i]) += 1; }" +
+		"as total = 0;" +
+		"while ((total < 10)) { total = (total + 1); }"
+	if program.String() != expected {
+		t.Errorf("Expected program %q. Got: %q", expected, program.String())
+	}
+}
+
+func TestAssignStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"1 = 2;", "Cannot assign to 1"},
+		{"x + 1 = 2;", "Cannot assign to (x + 1)"},
+		{"f() += 1;", "Cannot assign to f()"},
+		{"xs[1:] = ys;", "Cannot assign to (xs[1:])"},
+		{"(x) = 1;", ""},
+	}
+
+	for _, tt := range tests {
+		if tt.expectedError == "" {
+			par := New(lexer.New(tt.input))
+			par.Parse()
+			checkParseErrors(t, par)
+			continue
+		}
+		program := checkFirstError(t, tt.input, tt.expectedError)
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %d",
+				tt.input, len(program.Statements))
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	input := `
+	as = 1;
+	as a = 1;
+	as b = (2 + ;
+	while (a < b) {
+		a += ;
+		if (a) { break }
+		c.;
+	}
+	as d = [1, 2;
+	ret a;
+	struct S { x int, y: int }
+	as p = Point { x: 1 y: 2 };
+	as m = {"a": 1 2};
+	fn f(x: int) of { }
+	fn g(x int) { as y = 1; ret y; }
+	ret b;
+	`
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+
+	expectedErrors := []string{
+		"Expected next token to be IDENT. Got: = at 2:5",
+		"No prefix parse function for ; found at 4:14",
+		"No prefix parse function for ; found at 6:8",
+		"Expected next token to be IDENT. Got: ; at 8:5",
+		"Expected next token to be ,. Got: ; at 10:14",
+		"Expected next token to be :. Got: IDENT at 12:15",
+		"Expected next token to be ,. Got: IDENT at 13:22",
+		"Expected next token to be ,. Got: INT at 14:17",
+		"Expected a type. Got: { at 15:18",
+		"Expected next token to be :. Got: IDENT at 16:9",
+	}
+
+	errors := par.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("Expected %d errors. Got: %q", len(expectedErrors), errors)
+	}
+	for i, expected := range expectedErrors {
+		if errors[i].String() != expected {
+			t.Errorf("Expected error %q. Got: %q", expected, errors[i])
+		}
+	}
+
+	expected := "as a = 1;while ((a < b)) { ifa break; }ret a;ret b;"
+	if program.String() != expected {
+		t.Errorf("Expected program %q. Got: %q", expected, program.String())
+	}
+}
+
+func TestUnclosedBlocks(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedHints []string
+	}{
+		{"if (x) { 1", []string{"The block at 1:8 is not closed."}},
+		{"fn f() { ret", []string{"The block at 1:8 is not closed."}},
+		{"{", []string{"The block at 1:1 is not closed."}},
+		{"while (x) { if (y) { 1", []string{
+			"The block at 1:20 is not closed.",
+			"The block at 1:11 is not closed.",
+		}},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+
+		errors := par.Errors()
+		if len(errors) != len(tt.expectedHints) {
+			t.Fatalf("Expected %d errors for %q. Got: %q", len(tt.expectedHints), tt.input, errors)
+		}
+		for i, hint := range tt.expectedHints {
+			if errors[i].Message != "Expected next token to be }. Got: EOF" {
+				t.Errorf("Expected an unclosed block error for %q. Got: %q", tt.input, errors[i].Message)
+			}
+			if errors[i].Hint != hint {
+				t.Errorf("Expected hint %q for %q. Got: %q", hint, tt.input, errors[i].Hint)
+			}
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %q", tt.input, program.String())
+		}
+	}
+}
+
+// Every prefix of the input is parsed, to make sure that the parser stops on any truncated input.
+func TestParsingTerminates(t *testing.T) {
+	input := `
+	/// A point.
+	struct Point { x: int, y: int, }
+	interface Shape { area: () float; scale: (float) Shape }
+	fn add(a: Point, b: Point) of Point {
+		ret Point { x: a.x + b.x, y: a.y + b.y };
+	}
+	as points: []Point = [Point { x: 1, y: 2 }, Point { x: 3, y: 4 }];
+	as config = { "name": "ae", "sizes": [1, 2][0:1] };
+	for p in points {
+		while (p.x < 10 && !false) { p.x += 1; if (p.y) { continue } else { break } }
+	}
+	as f = fn(x, y) { x * (y - 1) % 2 };
+	f(1, 2)(3);
+	`
+
+	for end := 0; end <= len(input); end++ {
+		done := make(chan bool)
+		go func() {
+			lex := lexer.New(input[:end])
+			lex.SetMode(lexer.ScanComments)
+			par := New(lex)
+			par.Parse()
+			done <- true
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Parsing did not stop for the input: %q", input[:end])
+		}
+	}
+}
+
+func TestIncDecStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedTarget string
+		expectedOp     string
+	}{
+		{"x++;", "x", "++"},
+		{"x--", "x", "--"},
+		{"xs[i]++;", "(xs[i])", "++"},
+		{"p.count--;", "p.count", "--"},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+		checkParseErrors(t, par)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("Expected one program statement. Got: %d",
+				len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.IncDecStatement)
+		if !ok {
+			t.Fatalf("Expected an IncDecStatement. Got: %T",
+				program.Statements[0])
+		}
+
+		if statement.Target.String() != tt.expectedTarget {
+			t.Errorf("Expected target %q. Got: %q",
+				tt.expectedTarget, statement.Target.String())
+		}
+		if statement.Operator != tt.expectedOp {
+			t.Errorf("Expected operator %q. Got: %q",
+				tt.expectedOp, statement.Operator)
+		}
+	}
+}
+
+func TestIncDecStatementsWithInsertedSemicolons(t *testing.T) {
+	input := `
+	while (i < 10) {
+		i++
+		total--
+	}
+	`
+
+	lex := lexer.New(input)
+	lex.SetMode(lexer.InsertSemis)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	expected := "while ((i < 10)) { i++;total--; }"
+	if program.String() != expected {
+		t.Errorf("Expected program %q. Got: %q", expected, program.String())
+	}
+}
+
+func TestIncDecStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"1++;", "Cannot increment 1"},
+		{"f()--;", "Cannot decrement f()"},
+		{"(a + b)++", "Cannot increment (a + b)"},
+		{"f(x++);", "Expected next token to be ,. Got: ++"},
+	}
+
+	for _, tt := range tests {
+		program := checkFirstError(t, tt.input, tt.expectedError)
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %d",
+				tt.input, len(program.Statements))
+		}
+	}
+}
+
+func TestPipeExpressionParsing(t *testing.T) {
+	input := "xs |> map(f) |> format()"
+
+	lex := lexer.New(input)
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	outer, ok := statement.Expression.(*ast.PipeExpression)
+	if !ok {
+		t.Fatalf("Expected a PipeExpression. Got: %T", statement.Expression)
+	}
+
+	if !testIdentifier(t, outer.Call.Function, "format") || len(outer.Call.Arguments) != 0 {
+		t.Fatalf("Expected a format() call. Got: %s", outer.Call)
+	}
+
+	inner, ok := outer.Left.(*ast.PipeExpression)
+	if !ok {
+		t.Fatalf("Expected a PipeExpression on the left. Got: %T", outer.Left)
+	}
+
+	if !testIdentifier(t, inner.Left, "xs") {
+		return
+	}
+
+	expected := "format(map(xs, f))"
+	desugared := outer.Desugar()
+	desugared.Arguments[0] = inner.Desugar()
+	if desugared.String() != expected {
+		t.Errorf("Expected the desugared call %q. Got: %q", expected, desugared.String())
+	}
+}
+
+func TestPipeExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"xs |> f;", "Expected a call after |>. Got: f"},
+		{"xs |> f() + 1;", "Expected a call after |>. Got: (f() + 1)"},
+		{"xs |> [1, 2];", "Expected a call after |>. Got: [1, 2]"},
+		{"xs |> ;", "No prefix parse function for ; found"},
+	}
+
+	for _, tt := range tests {
+		program := checkFirstError(t, tt.input, tt.expectedError)
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %d",
+				tt.input, len(program.Statements))
+		}
+	}
+}
+
+// A "--" token is only an operator at the end of a statement, so "a--b" is not silently split in two statements.
+func TestDecrementBeforeOperand(t *testing.T) {
+	lex := lexer.New("a - -b;")
+	par := New(lex)
+	program := par.Parse()
+	checkParseErrors(t, par)
+
+	if program.String() != "(a - (-b))" {
+		t.Errorf("Expected program %q. Got: %q", "(a - (-b))", program.String())
+	}
+
+	tests := []struct {
+		input         string
+		expectedError string
+		expectedHint  string
+	}{
+		{"a--b;", "Expected the end of the statement after --. Got: IDENT",
+			`Separate the minus signs to subtract a negative value, as in "a - -b".`},
+		{"5--3;", "Expected the end of the statement after --. Got: INT",
+			`Separate the minus signs to subtract a negative value, as in "a - -b".`},
+		{"a++b;", "Expected the end of the statement after ++. Got: IDENT", ""},
+	}
+
+	for _, tt := range tests {
+		lex := lexer.New(tt.input)
+		par := New(lex)
+		program := par.Parse()
+
+		errors := par.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("Expected one error for %q. Got: %q", tt.input, errors)
+		}
+		if errors[0].Message != tt.expectedError {
+			t.Errorf("Expected error %q for %q. Got: %q",
+				tt.expectedError, tt.input, errors[0].Message)
+		}
+		if errors[0].Hint != tt.expectedHint {
+			t.Errorf("Expected hint %q for %q. Got: %q",
+				tt.expectedHint, tt.input, errors[0].Hint)
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf("Expected no statements for %q. Got: %q", tt.input, program.String())
+		}
+	}
+}
@@ -1,60 +1,118 @@
 package token
 
+import "fmt"
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Start   Position // Position of the first char of the token.
+	End     Position // Position right after the last char of the token.
 }
 
 type TokenType string
 
+// Position is a location in the source input.
+type Position struct {
+	Line   int // Line number, starting at 1.
+	Column int // Column number, starting at 1.
+	Offset int // Byte offset, starting at 0.
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
+
 // Specifying constant token types.
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 
+	// Comments, only emitted by the lexer on request.
+	COMMENT     = "COMMENT"     // A "//" or "/* */" comment.
+	DOC_COMMENT = "DOC_COMMENT" // A "///" doc comment.
+
 	// Identifiers & literals.
-	IDENT = "IDENT" // Identifier token.
-	INT   = "INT"   // Integer type.
+	IDENT  = "IDENT"  // Identifier token.
+	INT    = "INT"    // Integer type.
+	FLOAT  = "FLOAT"  // Floating-point type.
+	STRING = "STRING" // String type.
 
 	// Operators.
 	ASSIGN   = "="
 	PLUS     = "+"
 	MINUS    = "-"
 	ASTERISK = "*"
+	POWER    = "**"
 	SLASH    = "/"
+	PERCENT  = "%"
 	BANG     = "!"
 	LT       = "<"
 	GT       = ">"
+	LTE      = "<="
+	GTE      = ">="
 	EQUALS   = "=="
 	NEQUALS  = "!="
+	AND      = "&&"
+	OR       = "||"
+	PIPE     = "|>"
+
+	// Compound assignment operators.
+	PLUS_ASSIGN     = "+="
+	MINUS_ASSIGN    = "-="
+	ASTERISK_ASSIGN = "*="
+	SLASH_ASSIGN    = "/="
+
+	// Increment and decrement operators.
+	INCREMENT = "++"
+	DECREMENT = "--"
 
 	// Delimiters.
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	DOT       = "."
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	// Keywords.
-	FUNCTION = "FUNCTION"
-	DECLARE  = "DECLARE"
-	RETURN   = "RETURN"
-	IF       = "IF"
-	ELSE     = "ELSE"
-	TRUE     = "TRUE"
-	FALSE    = "FALSE"
+	FUNCTION  = "FUNCTION"
+	DECLARE   = "DECLARE"
+	RETURN    = "RETURN"
+	IF        = "IF"
+	ELSE      = "ELSE"
+	TRUE      = "TRUE"
+	FALSE     = "FALSE"
+	OF        = "OF"
+	STRUCT    = "STRUCT"
+	INTERFACE = "INTERFACE"
+	WHILE     = "WHILE"
+	FOR       = "FOR"
+	IN        = "IN"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
 )
 
 var keywords = map[string]TokenType{
-	"fn":    FUNCTION,
-	"as":    DECLARE,
-	"ret":   RETURN,
-	"if":    IF,
-	"else":  ELSE,
-	"true":  TRUE,
-	"false": FALSE,
+	"fn":        FUNCTION,
+	"as":        DECLARE,
+	"ret":       RETURN,
+	"if":        IF,
+	"else":      ELSE,
+	"true":      TRUE,
+	"false":     FALSE,
+	"of":        OF,
+	"struct":    STRUCT,
+	"interface": INTERFACE,
+	"while":     WHILE,
+	"for":       FOR,
+	"in":        IN,
+	"break":     BREAK,
+	"continue":  CONTINUE,
 }
 
 // LookupIdent looks for an identifier and if it's a keyword, return it's representation.